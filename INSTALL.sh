@@ -9,8 +9,8 @@ fi
 set -e
 
 # Verificação de dependências
-echo "🔎 Verificando dependências (virt-install, virsh, wget, curl)..."
-REQUIRED_CMDS=(virt-install virsh wget curl)
+echo "🔎 Verificando dependências (virt-install, virsh, curl)..."
+REQUIRED_CMDS=(virt-install virsh curl)
 for cmd in "${REQUIRED_CMDS[@]}"; do
   if ! command -v "$cmd" >/dev/null 2>&1; then
     echo "❌ Erro: o comando '$cmd' não está instalado. Por favor, instale-o antes de continuar."
@@ -79,48 +79,12 @@ else
 fi
 
 
-# 5. Baixar arquivos de template (apenas os que não existirem)
-echo "🔄 Verificando arquivos de template..."
-TEMPLATES=(
-  "almalinux10.ini"
-  "debian13.ini"
-  "fedora43.ini"
-  "meta-data.tmpl"
-  "network-config-almalinux.tmpl"
-  "network-config.tmpl"
-  "ubuntu24.04.ini"
-  "user-data.tmpl"
-)
-TEMPLATE_BASE="https://raw.githubusercontent.com/paulozagaloneves/kvm-compose/main/templates"
-
-downloaded=0
-already_exist=0
-
-for tmpl in "${TEMPLATES[@]}"; do
-  if [ ! -f "$CONFIG_DIR/templates/$tmpl" ]; then
-    echo "  ⬇️  Baixando $tmpl..."
-    if curl -sS -L "$TEMPLATE_BASE/$tmpl" -o "$CONFIG_DIR/templates/$tmpl"; then
-      ((downloaded++))
-    else
-      echo "  ❌ Falha ao baixar $tmpl"
-    fi
-  else
-    ((already_exist++))
-  fi
-done
-
-if [ $downloaded -gt 0 ]; then
-  echo "✅ $downloaded novos templates baixados para $CONFIG_DIR/templates"
-fi
-if [ $already_exist -gt 0 ]; then
-  echo "ℹ️  $already_exist templates já existentes foram mantidos"
-fi
-
-echo
-echo "ℹ️  Você pode customizar os templates cloud-init (*.tmpl) localizados em: $CONFIG_DIR/templates"
-echo "   Templates disponíveis: meta-data.tmpl, network-config-almalinux.tmpl, network-config.tmpl, user-data.tmpl"
+# 5. Templates
+# As distros e os templates cloud-init padrão vêm embutidos no binário
+echo "ℹ️  Para customizar os templates cloud-init (*.tmpl), execute: kvm-compose templates init --global"
+echo "   Os templates serão escritos em: $CONFIG_DIR/templates"
 echo
-echo "💡 Para adicionar suporte a novas distribuições, basta criar um novo arquivo .ini na pasta de templates com as configurações desejadas."
+echo "💡 Para adicionar suporte a novas distribuições, use: kvm-compose distro add <nome> --url <imagem> --os-variant <variante>"
 echo
 echo "Exemplo básico de kvm-compose.yaml:"
 cat <<EOF
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// bsdChecksumLine reconhece linhas no formato "SHA256 (ficheiro) = hash"
+var bsdChecksumLine = regexp.MustCompile(`^SHA(256|512) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+// expectedChecksum retorna o hash esperado da imagem da distro (SHA256 do INI ou
+// obtido do CHECKSUM_URL), ou "" se a distro não definir nenhum
+func expectedChecksum(info *DistroInfo) (string, error) {
+	if info.SHA256 != "" {
+		return strings.ToLower(info.SHA256), nil
+	}
+	if info.ChecksumURL == "" {
+		return "", nil
+	}
+	content, err := fetchURL(info.ChecksumURL)
+	if err != nil {
+		return "", fmt.Errorf("erro ao baixar %s: %v", info.ChecksumURL, err)
+	}
+	names := []string{info.Source, filepath.Base(info.URL)}
+	for _, name := range names {
+		if sum := parseChecksumFile(content, name); sum != "" {
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("%s não contém o checksum de %s", info.ChecksumURL, info.Source)
+}
+
+// parseChecksumFile procura o hash de um ficheiro num ficheiro de checksums
+// (formato GNU "hash  ficheiro" ou BSD "SHA256 (ficheiro) = hash")
+func parseChecksumFile(content, filename string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if m := bsdChecksumLine.FindStringSubmatch(line); m != nil {
+			if m[2] == filename {
+				return strings.ToLower(m[3])
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == filename {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return ""
+}
+
+// fileChecksum calcula o hash de um ficheiro; o algoritmo (SHA256 ou SHA512)
+// é escolhido pelo tamanho do hash esperado
+func fileChecksum(path, expected string) (string, error) {
+	switch len(expected) {
+	case sha256.Size * 2:
+		return hashFile(path, sha256.New())
+	case sha512.Size * 2:
+		return hashFile(path, sha512.New())
+	}
+	return "", fmt.Errorf("checksum com formato desconhecido: %s", expected)
+}
+
+// sha256File calcula o SHA256 de um ficheiro
+func sha256File(path string) (string, error) {
+	return hashFile(path, sha256.New())
+}
+
+// hashFile calcula o hash h do conteúdo de um ficheiro, em hexadecimal
+func hashFile(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// verifyImage compara o hash de uma imagem com o esperado
+func verifyImage(path, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	color.Cyan("🔐 Verificando checksum de %s...", filepath.Base(path))
+	sum, err := fileChecksum(path, expected)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum inválido para %s: esperado %s, obtido %s", path, expected, sum)
+	}
+	color.Green("✅ Checksum válido")
+	return nil
+}
@@ -4,171 +4,325 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"os/user"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
 )
 
-// createCloudInitFiles cria os arquivos cloud-init para uma VM
-func (kvm *KVMCompose) createCloudInitFiles(vm *VM) error {
-	// Obter valores padrão da configuração
-	defaultUsername, defaultSSHKeyFile, defaultGateway, defaultNameservers := kvm.getDefaultValues()
+// cloudInitFiles contém os ficheiros cloud-init renderizados de uma VM
+type cloudInitFiles struct {
+	UserData      string
+	NetworkConfig string
+	MetaData      string
+}
 
-	// Aplicar valores padrão
-	if vm.Memory == 0 {
-		vm.Memory = 4096
+// validate verifica se cada documento renderizado é YAML válido (user-data em script "#!" é aceite)
+func (f *cloudInitFiles) validate() error {
+	for _, file := range f.isoFiles() {
+		if file.Name == "user-data" && strings.HasPrefix(string(file.Data), "#!") {
+			continue
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal(file.Data, &doc); err != nil {
+			return fmt.Errorf("%s inválido: %v", file.Name, err)
+		}
 	}
-	if vm.VCPUs == 0 {
-		vm.VCPUs = 4
+	return nil
+}
+
+// save grava os ficheiros renderizados num diretório
+func (f *cloudInitFiles) save(dir string) error {
+	for _, file := range f.isoFiles() {
+		// O user-data contém as chaves de host privadas e o hash da password
+		if err := os.WriteFile(filepath.Join(dir, file.Name), file.Data, 0600); err != nil {
+			return err
+		}
 	}
-	if vm.DiskSize == 0 {
-		vm.DiskSize = 20
+	return nil
+}
+
+// isoFiles retorna os ficheiros com os nomes esperados pela datasource NoCloud
+func (f *cloudInitFiles) isoFiles() []isoFile {
+	return []isoFile{
+		{Name: "user-data", Data: []byte(f.UserData)},
+		{Name: "network-config", Data: []byte(f.NetworkConfig)},
+		{Name: "meta-data", Data: []byte(f.MetaData)},
 	}
-	if vm.Username == "" {
-		vm.Username = defaultUsername
+}
+
+// renderCloudInit renderiza os ficheiros cloud-init de uma VM. Com dryRun nada é gravado
+// (ex.: a chave SSH do projeto não é gerada)
+func (kvm *KVMCompose) renderCloudInit(vm *VM, dryRun bool) (*cloudInitFiles, error) {
+	// Aplicar valores padrão
+	kvm.applyVMDefaults(vm)
+
+	// Os templates e o nome da interface podem vir da distro
+	distroInfo, err := vmDistroInfo(vm)
+	if err != nil {
+		distroInfo = &DistroInfo{}
 	}
+	ctx := kvm.templateContext(vm, distroInfo)
 
-	// Determinar qual arquivo de chave SSH usar
-	sshKeyFile := vm.SSHKeyFile
-	if sshKeyFile == "" {
-		sshKeyFile = defaultSSHKeyFile
+	// Chaves SSH e password do utilizador
+	if ctx.SSHAuthorizedKeys, err = kvm.vmAuthorizedKeys(vm, !dryRun); err != nil {
+		return nil, fmt.Errorf("VM '%s': %v", vm.Name, err)
+	}
+	if len(ctx.SSHAuthorizedKeys) > 0 {
+		ctx.SSHPublicKey = ctx.SSHAuthorizedKeys[0]
+	}
+	if ctx.PasswordHash, err = kvm.vmPasswordHash(vm); err != nil {
+		return nil, fmt.Errorf("VM '%s': %v", vm.Name, err)
+	}
+	// Sem password, a conta fica bloqueada para login por password
+	ctx.LockPasswd = ctx.PasswordHash == ""
+	if vm.LockPasswd != nil {
+		ctx.LockPasswd = *vm.LockPasswd
+	}
+	if vm.SSHPwauth != nil {
+		ctx.SSHPwauth = *vm.SSHPwauth
 	}
 
-	// Ler chave SSH
-	sshKey := ""
-	if sshKeyFile != "" {
-		key, err := readSSHKey(sshKeyFile)
-		if err != nil {
-			color.Yellow("⚠️  Aviso: Não foi possível ler a chave SSH %s: %v", sshKeyFile, err)
-		} else {
-			sshKey = key
-		}
+	// 1. user-data
+	userDataContent, err := renderTemplate(vm, distroInfo, "user-data", ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Estrutura de dados para os templates
-	type userDataVars struct {
-		Username     string
-		SSHPublicKey string
+	if !isCloudConfig(userDataContent) {
+		color.Yellow("⚠️  O user-data de %s não é #cloud-config: discos, chaves de host, phone_home e cloud_init não são aplicados", vm.Name)
 	}
-	type networkConfigVars struct {
-		GuestIPv4        string
-		GuestGateway4    string
-		GuestNameservers []string
+
+	// Discos de dados com ponto de montagem
+	disks, err := kvm.resolveDataDisks(vm)
+	if err != nil {
+		return nil, err
 	}
-	type metaDataVars struct {
-		InstanceID string
-		Hostname   string
+	userDataContent, err = mergeUserData(userDataContent, diskCloudConfig(disks))
+	if err != nil {
+		return nil, err
 	}
 
-	// Função auxiliar para procurar template
-	findTemplate := func(filename string) (string, error) {
-		// 1. Procurar na pasta local templates/
-		local := filepath.Join("templates", filename)
-		if _, err := os.Stat(local); err == nil {
-			return local, nil
-		}
-		// 2. Procurar em ~/.config/kvm-compose/templates/
-		usr, _ := user.Current()
-		if usr != nil {
-			home := filepath.Join(usr.HomeDir, ".config", "kvm-compose", "templates", filename)
-			if _, err := os.Stat(home); err == nil {
-				return home, nil
-			}
-		}
-		return "", fmt.Errorf("template %s não encontrado", filename)
+	// Chaves de host SSH fixas, para que o known_hosts do projeto sobreviva a down/up
+	hostKeys, err := kvm.vmHostKeys(vm.Name, !dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("VM '%s': %v", vm.Name, err)
+	}
+	if len(hostKeys) == 0 {
+		color.Yellow("⚠️  VM %s sem chaves de host: serão geradas no up", vm.Name)
+	}
+	if userDataContent, err = mergeUserData(userDataContent, hostKeysCloudConfig(hostKeys)); err != nil {
+		return nil, err
 	}
 
-	// 1. user-data
-	userDataFile, err := findTemplate("user-data.tmpl")
-	var userDataContent string
-	if err == nil {
-		tmpl, err := template.ParseFiles(userDataFile)
+	// Aviso de conclusão do cloud-init (up --wait)
+	if kvm.phoneHome != nil && !dryRun {
+		phoneHome, err := kvm.phoneHome.cloudConfig(vm)
 		if err != nil {
-			return err
+			color.Yellow("⚠️  VM %s sem phone_home: %v", vm.Name, err)
+		} else if userDataContent, err = mergeUserData(userDataContent, phoneHome); err != nil {
+			return nil, err
 		}
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, userDataVars{Username: vm.Username, SSHPublicKey: sshKey})
-		if err != nil {
-			return err
+	}
+
+	// Personalizações cloud_init da VM
+	parts, err := vm.CloudInit.userDataParts()
+	if err != nil {
+		return nil, fmt.Errorf("VM '%s': cloud_init: %v", vm.Name, err)
+	}
+	for _, extra := range parts {
+		if userDataContent, err = mergeUserData(userDataContent, extra); err != nil {
+			return nil, fmt.Errorf("VM '%s': cloud_init: %v", vm.Name, err)
 		}
-		userDataContent = buf.String()
-	} else {
-		// Fallback para template embutido
-		userDataContent = fmt.Sprintf(`#cloud-config\nusers:\n  - name: %s\n    ssh_authorized_keys:\n      - %s\n    sudo: ['ALL=(ALL) NOPASSWD:ALL']\n    shell: /bin/bash\n    lock_passwd: false\n`, vm.Username, sshKey)
 	}
-	err = os.WriteFile(vm.Name+"-user-data.yaml", []byte(userDataContent), 0644)
+
+	// 2. network-config
+	networkConfigContent, err := renderTemplate(vm, distroInfo, "network-config", ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Criar network-config
-	network := vm.Networks[0] // Assumindo apenas uma rede por VM
-	if network.HostBridge == "" {
-		network.HostBridge = "br0"
+	// 3. meta-data
+	metaDataContent, err := renderTemplate(vm, distroInfo, "meta-data", ctx)
+	if err != nil {
+		return nil, err
 	}
-	if network.GuestGateway4 == "" {
-		network.GuestGateway4 = defaultGateway
+	files := &cloudInitFiles{
+		UserData:      userDataContent,
+		NetworkConfig: networkConfigContent,
+		MetaData:      metaDataContent,
 	}
-	if len(network.GuestNameservers) == 0 {
-		network.GuestNameservers = defaultNameservers
+	if err := files.validate(); err != nil {
+		return nil, fmt.Errorf("VM '%s': %v", vm.Name, err)
 	}
+	return files, nil
+}
 
-	// 2. network-config
-	networkConfigFile, err := findTemplate("network-config.tmpl")
-	var networkConfigContent string
-	if err == nil {
+// cloudInitTemplates são os templates cloud-init gerados para cada VM
+var cloudInitTemplates = []string{"user-data", "network-config", "meta-data"}
 
-		tmpl, err := template.ParseFiles(networkConfigFile)
-		if err != nil {
-			return err
+// distroFamily retorna a família de uma distro: o nome sem a versão (ex.: almalinux10 → almalinux)
+func distroFamily(distro string) string {
+	return strings.TrimRight(distro, "0123456789.")
+}
+
+// templateCandidates retorna os templates a tentar para a VM, do mais específico ao genérico:
+// <template>-<vm>.tmpl, NETWORK_TEMPLATE da distro, <template>-<distro>.tmpl, <template>-<família>.tmpl e <template>.tmpl
+func templateCandidates(vm *VM, distroInfo *DistroInfo, name string) []string {
+	candidates := []string{fmt.Sprintf("%s-%s.tmpl", name, vm.Name)}
+	if name == "network-config" && distroInfo.NetworkTemplate != "" {
+		candidates = append(candidates, distroInfo.NetworkTemplate)
+	}
+	if vm.Distro != "" {
+		candidates = append(candidates, fmt.Sprintf("%s-%s.tmpl", name, vm.Distro))
+		if family := distroFamily(vm.Distro); family != "" && family != vm.Distro {
+			candidates = append(candidates, fmt.Sprintf("%s-%s.tmpl", name, family))
+		}
+	}
+	return append(candidates, name+".tmpl")
+}
+
+// resolveTemplate lê o template cloud-init da VM: o indicado em templates (caminho ou nome de
+// template) ou o primeiro candidato encontrado. Retorna o conteúdo e a origem.
+func resolveTemplate(vm *VM, distroInfo *DistroInfo, name string) ([]byte, string, error) {
+	if override, ok := vm.Templates[name]; ok {
+		path := expandPath(override)
+		if data, err := os.ReadFile(path); err == nil {
+			return data, path, nil
 		}
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, networkConfigVars{
-			GuestIPv4:        network.GuestIPv4,
-			GuestGateway4:    network.GuestGateway4,
-			GuestNameservers: network.GuestNameservers,
-		})
+		data, origin, err := readTemplateFile(override)
 		if err != nil {
-			return err
+			return nil, "", fmt.Errorf("VM '%s': template de %s '%s' não encontrado", vm.Name, name, override)
 		}
-		networkConfigContent = buf.String()
-	} else {
-		nsYAML := ""
-		for _, ns := range network.GuestNameservers {
-			nsYAML += fmt.Sprintf("        - %s\n", ns)
+		return data, origin, nil
+	}
+	for _, candidate := range templateCandidates(vm, distroInfo, name) {
+		if data, origin, err := readTemplateFile(candidate); err == nil {
+			if origin == embeddedSource {
+				origin = fmt.Sprintf("%s (%s)", embeddedSource, candidate)
+			}
+			return data, origin, nil
 		}
-		networkConfigContent = fmt.Sprintf(`version: 2\nethernets:\n  enp1s0:\n    dhcp4: false\n    addresses: \n      - %s/24\n    gateway4: %s\n    nameservers:\n      addresses:\n%s`, network.GuestIPv4, network.GuestGateway4, nsYAML)
 	}
-	err = os.WriteFile(vm.Name+"-network-config.yaml", []byte(networkConfigContent), 0644)
+	return nil, "", fmt.Errorf("template %s.tmpl não encontrado", name)
+}
+
+// renderTemplate renderiza o template cloud-init da VM (local, do utilizador ou embutido)
+func renderTemplate(vm *VM, distroInfo *DistroInfo, name string, data interface{}) (string, error) {
+	content, origin, err := resolveTemplate(vm, distroInfo, name)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(string(content))
 	if err != nil {
-		return err
+		return "", fmt.Errorf("erro no template %s (%s): %v", name, origin, err)
 	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("erro ao renderizar %s (%s): %v", name, origin, err)
+	}
+	return buf.String(), nil
+}
 
-	// 3. meta-data
-	metaDataFile, err := findTemplate("meta-data.tmpl")
-	var metaDataContent string
-	if err == nil {
-		tmpl, err := template.ParseFiles(metaDataFile)
-		if err != nil {
-			return err
-		}
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, metaDataVars{InstanceID: vm.Name, Hostname: vm.Name})
-		if err != nil {
-			return err
+// userDataParts retorna as personalizações da VM como cloud-config a juntar ao user-data:
+// primeiro os campos individuais e depois o user_data, que pode substituir qualquer chave
+func (c CloudInit) userDataParts() ([]map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if len(c.Packages) > 0 {
+		fields["packages"] = c.Packages
+	}
+	if len(c.Runcmd) > 0 {
+		fields["runcmd"] = c.Runcmd
+	}
+	if len(c.Bootcmd) > 0 {
+		fields["bootcmd"] = c.Bootcmd
+	}
+	if len(c.WriteFiles) > 0 {
+		fields["write_files"] = c.WriteFiles
+	}
+	if c.Timezone != "" {
+		fields["timezone"] = c.Timezone
+	}
+	parts := []map[string]interface{}{fields}
+
+	switch raw := c.UserData.(type) {
+	case nil:
+	case string:
+		userData := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(raw), &userData); err != nil {
+			return nil, fmt.Errorf("user_data inválido: %v", err)
 		}
-		metaDataContent = buf.String()
-	} else {
-		metaDataContent = fmt.Sprintf(`instance-id: %s\nlocal-hostname: %s\n`, vm.Name, vm.Name)
+		parts = append(parts, userData)
+	case map[string]interface{}:
+		parts = append(parts, raw)
+	default:
+		return nil, fmt.Errorf("user_data deve ser um mapa ou texto #cloud-config")
 	}
-	return os.WriteFile(vm.Name+"-meta-data.yaml", []byte(metaDataContent), 0644)
+	return parts, nil
 }
 
-// cleanupCloudInitFiles remove os arquivos temporários de cloud-init
-func cleanupCloudInitFiles(vmName string) {
-	os.Remove(vmName + "-user-data.yaml")
-	os.Remove(vmName + "-network-config.yaml")
-	os.Remove(vmName + "-meta-data.yaml")
+// mergeUserData junta chaves ao user-data renderizado: mapas são juntados recursivamente,
+// listas existentes são concatenadas e os restantes valores substituídos
+func mergeUserData(content string, extra map[string]interface{}) (string, error) {
+	if len(extra) == 0 || !isCloudConfig(content) {
+		return content, nil
+	}
+	data := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content), &data); err != nil {
+		return "", fmt.Errorf("user-data inválido: %v", err)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	// Normalizar os tipos (ex.: [][]string) para os mesmos do YAML lido
+	normalized, err := yaml.Marshal(extra)
+	if err != nil {
+		return "", err
+	}
+	extra = map[string]interface{}{}
+	if err := yaml.Unmarshal(normalized, &extra); err != nil {
+		return "", err
+	}
+	deepMerge(data, extra)
+
+	var buf bytes.Buffer
+	buf.WriteString("#cloud-config\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// isCloudConfig indica se o user-data é cloud-config (YAML), e não um script "#!",
+// um "#include" ou outro formato do cloud-init onde não se podem juntar chaves
+func isCloudConfig(content string) bool {
+	firstLine, _, _ := strings.Cut(strings.TrimLeft(content, " \t\r\n"), "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	if strings.HasPrefix(firstLine, "Content-Type:") {
+		return false
+	}
+	return !strings.HasPrefix(firstLine, "#") || firstLine == "#cloud-config"
+}
+
+// deepMerge junta src em dst: mapas recursivamente, listas concatenadas, restantes substituídos
+func deepMerge(dst, src map[string]interface{}) {
+	for key, value := range src {
+		switch added := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				deepMerge(existing, added)
+				continue
+			}
+		case []interface{}:
+			if existing, ok := dst[key].([]interface{}); ok {
+				dst[key] = append(existing, added...)
+				continue
+			}
+		}
+		dst[key] = value
+	}
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeepMerge(t *testing.T) {
+	dst := map[string]interface{}{
+		"timezone": "Europe/Lisbon",
+		"packages": []interface{}{"curl"},
+		"users":    map[string]interface{}{"name": "debian", "shell": "/bin/bash"},
+	}
+	src := map[string]interface{}{
+		"timezone": "UTC",
+		"packages": []interface{}{"git", "vim"},
+		"users":    map[string]interface{}{"shell": "/bin/zsh", "sudo": "ALL"},
+		"runcmd":   []interface{}{"echo ok"},
+	}
+	deepMerge(dst, src)
+
+	want := map[string]interface{}{
+		"timezone": "UTC",
+		"packages": []interface{}{"curl", "git", "vim"},
+		"users":    map[string]interface{}{"name": "debian", "shell": "/bin/zsh", "sudo": "ALL"},
+		"runcmd":   []interface{}{"echo ok"},
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("deepMerge = %v, esperado %v", dst, want)
+	}
+}
+
+func TestDeepMergeReplacesDifferentTypes(t *testing.T) {
+	dst := map[string]interface{}{"packages": "curl", "write_files": []interface{}{"a"}}
+	deepMerge(dst, map[string]interface{}{
+		"packages":    []interface{}{"git"},
+		"write_files": map[string]interface{}{"path": "/etc/motd"},
+	})
+	if !reflect.DeepEqual(dst["packages"], []interface{}{"git"}) {
+		t.Errorf("packages = %v, esperado lista substituída", dst["packages"])
+	}
+	if !reflect.DeepEqual(dst["write_files"], map[string]interface{}{"path": "/etc/motd"}) {
+		t.Errorf("write_files = %v, esperado mapa substituído", dst["write_files"])
+	}
+}
+
+func TestMergeUserData(t *testing.T) {
+	content := "#cloud-config\ntimezone: Europe/Lisbon\nruncmd:\n  - [ sh, -c, 'swapoff -a' ]\n"
+
+	same, err := mergeUserData(content, nil)
+	if err != nil || same != content {
+		t.Errorf("sem extras o user-data deve ficar igual, obtido %q (%v)", same, err)
+	}
+
+	merged, err := mergeUserData(content, map[string]interface{}{
+		"timezone": "UTC",
+		"runcmd":   [][]string{{"sh", "-c", "echo ok"}},
+		"packages": []string{"git"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(merged, "#cloud-config\n") {
+		t.Errorf("esperado cabeçalho #cloud-config, obtido %q", merged)
+	}
+	var got map[string]interface{}
+	if err := yaml.Unmarshal([]byte(merged), &got); err != nil {
+		t.Fatalf("user-data gerado inválido: %v", err)
+	}
+	want := map[string]interface{}{
+		"timezone": "UTC",
+		"runcmd": []interface{}{
+			[]interface{}{"sh", "-c", "swapoff -a"},
+			[]interface{}{"sh", "-c", "echo ok"},
+		},
+		"packages": []interface{}{"git"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeUserData = %v, esperado %v", got, want)
+	}
+
+	if _, err := mergeUserData("runcmd: [", map[string]interface{}{"a": 1}); err == nil {
+		t.Errorf("esperado erro com user-data inválido")
+	}
+}
+
+func TestMergeUserDataKeepsScripts(t *testing.T) {
+	extra := map[string]interface{}{"ssh_deletekeys": true}
+	for _, content := range []string{
+		"#!/bin/sh\necho ola > /tmp/ola\n",
+		"#include\nhttps://exemplo.pt/user-data\n",
+		"Content-Type: multipart/mixed; boundary=x\n",
+	} {
+		merged, err := mergeUserData(content, extra)
+		if err != nil {
+			t.Errorf("mergeUserData(%q): %v", content, err)
+		}
+		if merged != content {
+			t.Errorf("user-data não cloud-config alterado: %q", merged)
+		}
+	}
+}
+
+func TestIsCloudConfig(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"#cloud-config\ntimezone: UTC\n", true},
+		{"\n#cloud-config\r\n", true},
+		{"timezone: UTC\n", true},
+		{"", true},
+		{"#!/bin/bash\nls\n", false},
+		{"#include\nhttps://exemplo.pt\n", false},
+		{"#cloud-boothook\n", false},
+		{"Content-Type: multipart/mixed\n", false},
+	}
+	for _, tt := range tests {
+		if got := isCloudConfig(tt.content); got != tt.want {
+			t.Errorf("isCloudConfig(%q) = %v, esperado %v", tt.content, got, tt.want)
+		}
+	}
+}
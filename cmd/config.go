@@ -15,6 +15,8 @@ type KVMCompose struct {
 	composeFile string
 	config      Config
 	appConfig   *AppConfig
+	// phoneHome, quando ativo (up --wait), recebe os reports do cloud-init das VMs
+	phoneHome *phoneHomeServer
 }
 
 // NewKVMCompose cria uma nova instância do KVMCompose
@@ -35,46 +37,113 @@ type AppConfig struct {
 
 // MainConfig representa configurações principais
 type MainConfig struct {
-	Username   string `ini:"username"`
-	SSHKeyFile string `ini:"ssh_key_file"`
+	Username     string `ini:"username"`
+	SSHKeyFile   string `ini:"ssh_key_file"`
+	Password     string `ini:"password"`
+	PasswordFile string `ini:"password_file"`
 }
 
 // NetworkConfig representa configurações de rede
 type NetworkConfig struct {
 	Gateway     string `ini:"gateway"`
 	Nameservers string `ini:"nameservers"`
+	Subnet      string `ini:"subnet"`
+	IPRange     string `ini:"ip_range"`
 }
 
 // ImagesConfig representa configurações de imagens
 type ImagesConfig struct {
 	PathUpstreamImages string `ini:"path_upstream_images"`
 	PathVMImages       string `ini:"path_vm_images"`
+	DiskMode           string `ini:"disk_mode"`
+	Pool               string `ini:"pool"`
 }
 
 // VM representa uma máquina virtual no arquivo de configuração
 type VM struct {
-	Name       string    `yaml:"name"`
-	Distro     string    `yaml:"distro"`
-	Memory     int       `yaml:"memory"`
-	VCPUs      int       `yaml:"vcpus"`
-	DiskSize   int       `yaml:"disk_size"`
-	Username   string    `yaml:"username"`
-	Group      []string  `yaml:"group"`
-	SSHKeyFile string    `yaml:"ssh_key_file"`
-	Networks   []Network `yaml:"networks"`
+	Name              string            `yaml:"name"`
+	Distro            string            `yaml:"distro"`
+	Image             string            `yaml:"image"`
+	ImageSHA256       string            `yaml:"image_sha256"`
+	OSVariant         string            `yaml:"os_variant"`
+	Memory            int               `yaml:"memory"`
+	VCPUs             int               `yaml:"vcpus"`
+	DiskSize          int               `yaml:"disk_size"`
+	DiskMode          string            `yaml:"disk_mode"`
+	Username          string            `yaml:"username"`
+	Group             []string          `yaml:"group"`
+	SSHKeyFile        string            `yaml:"ssh_key_file"`
+	SSHAuthorizedKeys []string          `yaml:"ssh_authorized_keys"`
+	Password          string            `yaml:"password"`
+	PasswordFile      string            `yaml:"password_file"`
+	LockPasswd        *bool             `yaml:"lock_passwd"`
+	SSHPwauth         *bool             `yaml:"ssh_pwauth"`
+	Networks          []Network         `yaml:"networks"`
+	Disks             []Disk            `yaml:"disks"`
+	Volumes           []string          `yaml:"volumes"`
+	Templates         map[string]string `yaml:"templates"`
+	CloudInit         CloudInit         `yaml:"cloud_init"`
+}
+
+// CloudInit representa as personalizações de cloud-init de uma VM, juntadas ao user-data renderizado
+type CloudInit struct {
+	Packages   []string      `yaml:"packages"`
+	Runcmd     []interface{} `yaml:"runcmd"`
+	Bootcmd    []interface{} `yaml:"bootcmd"`
+	WriteFiles []interface{} `yaml:"write_files"`
+	Timezone   string        `yaml:"timezone"`
+	// UserData é cloud-config arbitrário (mapa YAML ou texto #cloud-config)
+	UserData interface{} `yaml:"user_data"`
+}
+
+// Disk representa um disco de dados adicional de uma VM
+type Disk struct {
+	Name       string `yaml:"name"`
+	Volume     string `yaml:"volume"`
+	Size       int    `yaml:"size"`
+	Format     string `yaml:"format"`
+	Bus        string `yaml:"bus"`
+	Mount      string `yaml:"mount"`
+	Filesystem string `yaml:"filesystem"`
+}
+
+// Volume representa um volume nomeado, que sobrevive ao down
+type Volume struct {
+	Size   int    `yaml:"size"`
+	Format string `yaml:"format"`
 }
 
 // Network representa a configuração de rede de uma VM
 type Network struct {
+	Network          string   `yaml:"network"`
 	HostBridge       string   `yaml:"host_bridge"`
+	MAC              string   `yaml:"mac"`
 	GuestIPv4        string   `yaml:"guest_ipv4"`
+	GuestPrefix      int      `yaml:"guest_prefix"`
 	GuestGateway4    string   `yaml:"guest_gateway4"`
 	GuestNameservers []string `yaml:"guest_nameservers"`
+
+	// leased indica que GuestIPv4 veio do IPAM e não do compose
+	leased bool
 }
 
-// Config representa o arquivo de configuração completo
+// ComposeNetwork representa uma rede declarada no nível superior do compose
+type ComposeNetwork struct {
+	HostBridge  string   `yaml:"host_bridge"`
+	Subnet      string   `yaml:"subnet"`
+	IPRange     string   `yaml:"ip_range"`
+	Gateway4    string   `yaml:"gateway4"`
+	Nameservers []string `yaml:"nameservers"`
+}
+
+// Config representa o arquivo de configuração completo.
+// O compose pode ser uma lista de VMs ou um mapa com name, networks e vms.
 type Config struct {
-	VMs []VM `yaml:",inline"`
+	Name     string                    `yaml:"name"`
+	Networks map[string]ComposeNetwork `yaml:"networks"`
+	Volumes  map[string]Volume         `yaml:"volumes"`
+	VMs      []VM                      `yaml:"vms"`
+	Vars     map[string]interface{}    `yaml:"vars"`
 }
 
 // loadAppConfig carrega o arquivo de configuração INI
@@ -142,6 +211,37 @@ func (kvm *KVMCompose) getDefaultValues() (string, string, string, []string) {
 	return username, sshKeyFile, gateway, nameservers
 }
 
+// applyVMDefaults aplica os valores padrão às configurações omitidas de uma VM
+func (kvm *KVMCompose) applyVMDefaults(vm *VM) {
+	if vm.Memory == 0 {
+		vm.Memory = 4096
+	}
+	if vm.VCPUs == 0 {
+		vm.VCPUs = 4
+	}
+	if vm.DiskSize == 0 {
+		vm.DiskSize = 20
+	}
+	if vm.Username == "" {
+		vm.Username = kvm.appConfig.Main.Username
+	}
+	if vm.DiskMode == "" {
+		vm.DiskMode = kvm.appConfig.Images.DiskMode
+	}
+	if vm.DiskMode == "" {
+		vm.DiskMode = "clone"
+	}
+}
+
+// loadOptionalConfig carrega o compose se existir. Só a falta do ficheiro é tolerada:
+// um compose com erros é reportado, para não ser tratado como um projeto sem VMs.
+func (kvm *KVMCompose) loadOptionalConfig() error {
+	if _, err := os.Stat(kvm.composeFile); os.IsNotExist(err) {
+		return nil
+	}
+	return kvm.loadConfig()
+}
+
 // loadConfig carrega o arquivo YAML de configuração
 func (kvm *KVMCompose) loadConfig() error {
 	data, err := os.ReadFile(kvm.composeFile)
@@ -149,10 +249,24 @@ func (kvm *KVMCompose) loadConfig() error {
 		return fmt.Errorf("erro ao ler arquivo %s: %v", kvm.composeFile, err)
 	}
 
-	err = yaml.Unmarshal(data, &kvm.config.VMs)
+	// Formato antigo (lista de VMs) ou formato com chaves de nível superior
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("erro ao fazer parse do YAML: %v", err)
+	}
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.SequenceNode {
+		err = doc.Content[0].Decode(&kvm.config.VMs)
+	} else if len(doc.Content) > 0 {
+		err = doc.Content[0].Decode(&kvm.config)
+	}
 	if err != nil {
 		return fmt.Errorf("erro ao fazer parse do YAML: %v", err)
 	}
 
-	return nil
+	if err := kvm.resolveMACs(); err != nil {
+		return err
+	}
+
+	// Preencher redes nomeadas e IPs já alocados pelo IPAM
+	return kvm.resolveNetworks(false, false)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// sshKeyPrefixes identificam uma chave pública escrita diretamente em ssh_authorized_keys
+var sshKeyPrefixes = []string{"ssh-", "ecdsa-", "sk-ssh-", "sk-ecdsa-"}
+
+// vmAuthorizedKeys retorna as chaves SSH públicas da VM: a do ssh_key_file (da VM ou do
+// config.ini) seguida das de ssh_authorized_keys, que aceita chaves, ficheiros e globs.
+// Sem nenhuma chave utilizável é usada a chave do projeto, gerada se create for true.
+func (kvm *KVMCompose) vmAuthorizedKeys(vm *VM, create bool) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	add := func(key string) {
+		key = strings.TrimSpace(key)
+		if key != "" && !strings.HasPrefix(key, "#") && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	sshKeyFile := vm.SSHKeyFile
+	if sshKeyFile == "" {
+		sshKeyFile = kvm.appConfig.Main.SSHKeyFile
+	}
+	if sshKeyFile != "" {
+		key, err := readSSHKey(sshKeyFile)
+		if err != nil {
+			color.Yellow("⚠️  Aviso: Não foi possível ler a chave SSH %s: %v", sshKeyFile, err)
+		} else {
+			add(key)
+		}
+	}
+
+	for _, entry := range vm.SSHAuthorizedKeys {
+		if isSSHPublicKey(entry) {
+			add(entry)
+			continue
+		}
+		matches, err := filepath.Glob(expandPath(entry))
+		if err != nil {
+			return nil, fmt.Errorf("ssh_authorized_keys: padrão inválido '%s': %v", entry, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("ssh_authorized_keys: nenhum ficheiro encontrado para '%s'", entry)
+		}
+		for _, match := range matches {
+			content, err := os.ReadFile(match)
+			if err != nil {
+				return nil, fmt.Errorf("ssh_authorized_keys: %v", err)
+			}
+			for _, line := range strings.Split(string(content), "\n") {
+				add(line)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		key, err := kvm.projectPublicKey(create)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			color.Yellow("⚠️  VM %s sem chaves SSH: a chave do projeto será gerada no up", vm.Name)
+		}
+		add(key)
+	}
+	return keys, nil
+}
+
+// isSSHPublicKey indica se o texto é uma chave pública SSH e não um caminho
+func isSSHPublicKey(value string) bool {
+	for _, prefix := range sshKeyPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// vmPasswordHash retorna o hash sha512-crypt da password da VM (password ou password_file,
+// da VM ou do config.ini), ou "" se nenhuma estiver definida. Hashes crypt são usados tal como estão.
+func (kvm *KVMCompose) vmPasswordHash(vm *VM) (string, error) {
+	password, passwordFile := vm.Password, vm.PasswordFile
+	if password == "" && passwordFile == "" {
+		password, passwordFile = kvm.appConfig.Main.Password, kvm.appConfig.Main.PasswordFile
+	}
+	if password == "" && passwordFile != "" {
+		content, err := os.ReadFile(expandPath(passwordFile))
+		if err != nil {
+			return "", fmt.Errorf("erro ao ler password_file: %v", err)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	}
+	if password == "" {
+		return "", nil
+	}
+	if isPasswordHash(password) {
+		return password, nil
+	}
+	return hashPassword(password)
+}
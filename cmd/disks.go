@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// createVMDisk cria o disco raiz da VM a partir da imagem base, conforme o disk_mode:
+// "clone" cria um overlay qcow2 com a imagem base como backing file, "copy" copia a imagem inteira
+func (kvm *KVMCompose) createVMDisk(vm *VM, baseImagePath, vmImagePath string) error {
+	if kvm.usePool() {
+		return kvm.createPoolVMDisk(vm, baseImagePath, vmImagePath)
+	}
+
+	size := fmt.Sprintf("%dG", vm.DiskSize)
+	switch vm.DiskMode {
+	case "clone":
+		baseFormat, err := imageFormat(baseImagePath)
+		if err != nil {
+			return fmt.Errorf("erro ao obter formato de %s: %v", baseImagePath, err)
+		}
+		color.Cyan("🧬 Criando clone: %s → %s", baseImagePath, vmImagePath)
+		if err := execCommand("qemu-img", "create", "-f", "qcow2", "-b", baseImagePath, "-F", baseFormat, vmImagePath, size); err != nil {
+			return fmt.Errorf("erro ao criar clone: %v", err)
+		}
+	case "copy":
+		color.Cyan("📋 Copiando: %s → %s", baseImagePath, vmImagePath)
+		if err := execCommand("cp", baseImagePath, vmImagePath); err != nil {
+			return fmt.Errorf("erro ao copiar imagem: %v", err)
+		}
+		// Redimensionar imagem para o tamanho configurado
+		color.Cyan("🔧 Redimensionando imagem %s para %s...", vmImagePath, size)
+		if err := execCommand("qemu-img", "resize", vmImagePath, size); err != nil {
+			return fmt.Errorf("erro ao redimensionar imagem: %v", err)
+		}
+	default:
+		return fmt.Errorf("disk_mode inválido '%s' (use clone ou copy)", vm.DiskMode)
+	}
+
+	// Sem chmod: ao arrancar a VM, o libvirt (dynamic_ownership) atribui o disco ao
+	// utilizador do QEMU, tal como o seed, que é gravado com 0600
+	return nil
+}
+
+// dataDisk representa um disco de dados já resolvido para criação e montagem
+type dataDisk struct {
+	Name       string
+	Volume     string
+	Path       string
+	Size       int
+	Format     string
+	Bus        string
+	Serial     string
+	Mount      string
+	Filesystem string
+}
+
+// Device retorna o caminho estável do disco dentro da VM (/dev/disk/by-id)
+func (d dataDisk) Device() string {
+	switch d.Bus {
+	case "scsi":
+		return "/dev/disk/by-id/scsi-0QEMU_QEMU_HARDDISK_" + d.Serial
+	case "sata":
+		return "/dev/disk/by-id/ata-QEMU_HARDDISK_" + d.Serial
+	default:
+		return "/dev/disk/by-id/virtio-" + d.Serial
+	}
+}
+
+// Label retorna o rótulo do sistema de ficheiros, cortado ao máximo que o mkfs aceita:
+// 12 caracteres em xfs, 11 em vfat e 16 em ext4 e nos restantes
+func (d dataDisk) Label() string {
+	limit := 16
+	switch d.Filesystem {
+	case "xfs":
+		limit = 12
+	case "vfat", "fat":
+		limit = 11
+	}
+	label := d.Name
+	if len(label) > limit {
+		label = label[:limit]
+	}
+	return label
+}
+
+// resolveDataDisks junta disks e volumes de uma VM numa lista de discos de dados
+func (kvm *KVMCompose) resolveDataDisks(vm *VM) ([]dataDisk, error) {
+	disks := append([]Disk{}, vm.Disks...)
+
+	// volumes no formato "volume:/ponto/de/montagem", como no docker compose
+	for _, spec := range vm.Volumes {
+		parts := strings.SplitN(spec, ":", 2)
+		disk := Disk{Volume: parts[0]}
+		if len(parts) == 2 {
+			disk.Mount = parts[1]
+		}
+		disks = append(disks, disk)
+	}
+
+	vmImagesDir := filepath.Dir(kvm.getVMImagePath(vm.Name))
+	var result []dataDisk
+	for i, disk := range disks {
+		d := dataDisk{
+			Name:       disk.Name,
+			Volume:     disk.Volume,
+			Size:       disk.Size,
+			Format:     disk.Format,
+			Bus:        disk.Bus,
+			Mount:      disk.Mount,
+			Filesystem: disk.Filesystem,
+			Serial:     fmt.Sprintf("kvmc-disk%d", i+1),
+		}
+
+		if disk.Volume != "" {
+			volume, ok := kvm.config.Volumes[disk.Volume]
+			if !ok {
+				return nil, fmt.Errorf("VM '%s': volume '%s' não declarado em volumes", vm.Name, disk.Volume)
+			}
+			if d.Name == "" {
+				d.Name = disk.Volume
+			}
+			if d.Size == 0 {
+				d.Size = volume.Size
+			}
+			if d.Format == "" {
+				d.Format = volume.Format
+			}
+		}
+		if d.Name == "" {
+			d.Name = fmt.Sprintf("disk%d", i+1)
+		}
+		if d.Size == 0 {
+			d.Size = 10
+		}
+		if d.Format == "" {
+			d.Format = "qcow2"
+		}
+		if d.Bus == "" {
+			d.Bus = "virtio"
+		}
+		if d.Filesystem == "" {
+			d.Filesystem = "ext4"
+		}
+
+		if d.Volume != "" {
+			d.Path = kvm.getVolumePath(d.Volume, d.Format)
+		} else {
+			d.Path = filepath.Join(vmImagesDir, fmt.Sprintf("%s-%s.%s", vm.Name, d.Name, d.Format))
+		}
+		result = append(result, d)
+	}
+
+	// Os discos montados são encontrados pelo rótulo, que tem de ser único na VM
+	labels := make(map[string]string)
+	for _, d := range result {
+		if d.Mount == "" {
+			continue
+		}
+		if other, ok := labels[d.Label()]; ok {
+			return nil, fmt.Errorf("VM '%s': os discos '%s' e '%s' ficam com o mesmo rótulo '%s' (use nomes mais curtos)", vm.Name, other, d.Name, d.Label())
+		}
+		labels[d.Label()] = d.Name
+	}
+	return result, nil
+}
+
+// getVolumePath retorna o caminho do ficheiro de um volume nomeado do projeto
+func (kvm *KVMCompose) getVolumePath(volume, format string) string {
+	if format == "" {
+		format = "qcow2"
+	}
+	vmImagesDir := expandPath(kvm.appConfig.Images.PathVMImages)
+	return filepath.Join(vmImagesDir, fmt.Sprintf("%s_%s.%s", kvm.projectName(), volume, format))
+}
+
+// createDataDisks cria os discos de dados que ainda não existem e retorna os que criou
+// (também em caso de erro, para que possam ser removidos)
+func (kvm *KVMCompose) createDataDisks(disks []dataDisk) ([]string, error) {
+	var created []string
+	for _, d := range disks {
+		if kvm.usePool() {
+			if volumeExists(kvm.appConfig.Images.Pool, filepath.Base(d.Path)) {
+				continue
+			}
+			if err := kvm.createPoolDataDisk(d); err != nil {
+				return created, err
+			}
+			created = append(created, d.Path)
+			continue
+		}
+		if _, err := os.Stat(d.Path); err == nil {
+			continue
+		}
+		if err := execCommand("qemu-img", "create", "-f", d.Format, d.Path, fmt.Sprintf("%dG", d.Size)); err != nil {
+			return created, fmt.Errorf("erro ao criar disco %s: %v", d.Path, err)
+		}
+		created = append(created, d.Path)
+	}
+	return created, nil
+}
+
+// diskCloudConfig gera as chaves disk_setup, fs_setup e mounts para os discos com ponto de montagem
+func diskCloudConfig(disks []dataDisk) map[string]interface{} {
+	diskSetup := map[string]interface{}{}
+	var fsSetup, mounts []interface{}
+	for _, d := range disks {
+		if d.Mount == "" {
+			continue
+		}
+		diskSetup[d.Device()] = map[string]interface{}{
+			"table_type": "gpt",
+			"layout":     true,
+			"overwrite":  false,
+		}
+		fsSetup = append(fsSetup, map[string]interface{}{
+			"label":      d.Label(),
+			"filesystem": d.Filesystem,
+			"device":     d.Device(),
+			"partition":  "auto",
+			"overwrite":  false,
+		})
+		mounts = append(mounts, []string{"LABEL=" + d.Label(), d.Mount, d.Filesystem, "defaults,nofail", "0", "2"})
+	}
+	if len(fsSetup) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"disk_setup": diskSetup,
+		"fs_setup":   fsSetup,
+		"mounts":     mounts,
+	}
+}
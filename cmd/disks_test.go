@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDataDiskLabel(t *testing.T) {
+	tests := []struct {
+		name, filesystem, want string
+	}{
+		{"dados", "ext4", "dados"},
+		{"postgres-data-volume", "ext4", "postgres-data-vo"},
+		{"postgres-data-volume", "xfs", "postgres-dat"},
+		{"postgres-data-volume", "vfat", "postgres-da"},
+		{"logs", "xfs", "logs"},
+	}
+	for _, tt := range tests {
+		d := dataDisk{Name: tt.name, Filesystem: tt.filesystem}
+		if got := d.Label(); got != tt.want {
+			t.Errorf("Label(%q, %s) = %q, esperado %q", tt.name, tt.filesystem, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDataDisksRejectsDuplicateLabels(t *testing.T) {
+	kvm := &KVMCompose{composeFile: "kvm-compose.yaml", appConfig: &AppConfig{}}
+	vm := &VM{Name: "db", Disks: []Disk{
+		{Name: "data-volume-1", Mount: "/srv/a", Filesystem: "xfs"},
+		{Name: "data-volume-2", Mount: "/srv/b", Filesystem: "xfs"},
+	}}
+	if _, err := kvm.resolveDataDisks(vm); err == nil || !strings.Contains(err.Error(), "mesmo rótulo") {
+		t.Errorf("esperado erro de rótulo repetido, obtido %v", err)
+	}
+
+	// Em ext4 cabem os 13 caracteres, e discos sem montagem não usam rótulo
+	vm.Disks[0].Filesystem, vm.Disks[1].Filesystem = "ext4", "ext4"
+	if _, err := kvm.resolveDataDisks(vm); err != nil {
+		t.Errorf("ext4: %v", err)
+	}
+	vm.Disks[0].Filesystem, vm.Disks[1].Filesystem, vm.Disks[1].Mount = "xfs", "xfs", ""
+	if _, err := kvm.resolveDataDisks(vm); err != nil {
+		t.Errorf("disco sem montagem: %v", err)
+	}
+}
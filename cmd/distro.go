@@ -1,49 +1,289 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/user"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/paulozagaloneves/kvm-compose/templates"
 	"gopkg.in/ini.v1"
 )
 
 // DistroInfo representa informações de uma distribuição
 type DistroInfo struct {
-	URL       string
-	Source    string
-	OSVariant string
+	URL         string
+	Source      string
+	OSVariant   string
+	SHA256      string
+	ChecksumURL string
+	Mirrors     []string
+	MaxAge      time.Duration
+
+	// Particularidades da distro aplicadas na criação do domínio
+	Console         string
+	Firmware        string
+	Machine         string
+	Arch            string
+	NICName         string
+	NetworkTemplate string
+	ExtraArgs       []string
+}
+
+// defaultNICName é o nome da interface de rede usado quando a distro não define NIC_NAME
+const defaultNICName = "enp1s0"
+
+// nicName retorna o nome da primeira interface de rede dentro da VM
+func (d *DistroInfo) nicName() string {
+	if d.NICName == "" {
+		return defaultNICName
+	}
+	return d.NICName
+}
+
+// nicNameAt retorna o nome da interface de índice i no network-config: a primeira usa o
+// NIC_NAME da distro; as seguintes seguem o mesmo padrão (eth1, eth2... ou enp2s0, enp3s0...)
+func (d *DistroInfo) nicNameAt(i int) string {
+	name := d.nicName()
+	if i == 0 {
+		return name
+	}
+	if strings.HasPrefix(name, "eth") {
+		return fmt.Sprintf("eth%d", i)
+	}
+	return fmt.Sprintf("enp%ds0", i+1)
+}
+
+// installArgs retorna os argumentos do virt-install específicos da distro
+func (d *DistroInfo) installArgs() []string {
+	var args []string
+	if d.Console != "" {
+		args = append(args, "--console", d.Console)
+	}
+	if d.Firmware == "uefi" {
+		args = append(args, "--boot", "uefi")
+	}
+	if d.Machine != "" {
+		args = append(args, "--machine", d.Machine)
+	}
+	if d.Arch != "" {
+		args = append(args, "--arch", d.Arch)
+	}
+	return append(args, d.ExtraArgs...)
+}
+
+// embeddedSource identifica os ficheiros embutidos no binário
+const embeddedSource = "embutido"
+
+// localTemplateDir é a pasta de templates do diretório atual, com prioridade sobre as restantes
+const localTemplateDir = "templates"
+
+// userTemplateDir retorna a pasta de templates do utilizador (~/.config/kvm-compose/templates)
+func userTemplateDir() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("erro ao obter diretório do utilizador: %v", err)
+	}
+	return filepath.Join(usr.HomeDir, ".config", "kvm-compose", "templates"), nil
+}
+
+// templateDirs retorna os diretórios onde procurar templates, por ordem de prioridade
+func templateDirs() []string {
+	// 1. Pasta local templates/
+	dirs := []string{localTemplateDir}
+	// 2. ~/.config/kvm-compose/templates/
+	if dir, err := userTemplateDir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// legacyTemplates são os sha256 dos ficheiros que o antigo INSTALL.sh copiava para
+// ~/.config/kvm-compose/templates. Essas cópias não foram alteradas pelo utilizador e
+// ficaram desatualizadas: são ignoradas em favor das versões embutidas.
+var legacyTemplates = map[string]string{
+	"almalinux10.ini":               "9a9bb6cf25302b63fa6dfdff95c2da9c0bd316e124203dfa4b96cdfecf7b03c3",
+	"debian13.ini":                  "210425dd68548c7df0699e314f85787bbda7e2b0eb54693b5b9290dc6b0fa413",
+	"fedora43.ini":                  "8e2299fa2488001980a48201e8fe525d77e17330f99588bb24660839fc7a995e",
+	"ubuntu24.04.ini":               "aac01097c0ea5d83f1d957735361b69cdf2e60828a3d59a6b0d268476cd62466",
+	"meta-data.tmpl":                "60c9551a182e20a3973427db0c894b51ef2872cd23b20ac21cc2ddc26c0e4583",
+	"network-config.tmpl":           "0ce1eebc20eb980b843021480fc565cc860f985b963067d850ab6a52c009b104",
+	"network-config-almalinux.tmpl": "31b3ecd77deece0c52de95176c7be7cf569b4804028339c2160292ccd251b426",
+	"user-data.tmpl":                "8d2fcec9c4cb29995dd93d3aebcd8a9662af763a07619387677fa934f2de10f3",
+}
+
+// legacyWarned evita repetir o aviso de template antigo para o mesmo ficheiro
+var legacyWarned sync.Map
+
+// isLegacyTemplate indica se o ficheiro é uma cópia inalterada de um template antigo do INSTALL.sh
+func isLegacyTemplate(path string) bool {
+	want, ok := legacyTemplates[filepath.Base(path)]
+	if !ok {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != want {
+		return false
+	}
+	if _, warned := legacyWarned.LoadOrStore(path, true); !warned {
+		color.Yellow("⚠️  %s é uma cópia antiga instalada pelo INSTALL.sh; a usar a versão embutida", path)
+		color.Yellow("   Apague-o ou atualize com 'kvm-compose templates init --global --force'")
+	}
+	return true
 }
 
 // Função auxiliar para procurar template
 func findTemplate(filename string) (string, error) {
-	// 1. Procurar na pasta local templates/
-	local := filepath.Join("templates", filename)
-	if _, err := os.Stat(local); err == nil {
-		return local, nil
-	}
-	// 2. Procurar em ~/.config/kvm-compose/templates/
-	usr, _ := user.Current()
-	if usr != nil {
-		home := filepath.Join(usr.HomeDir, ".config", "kvm-compose", "templates", filename)
-		if _, err := os.Stat(home); err == nil {
-			return home, nil
+	for _, dir := range templateDirs() {
+		path := filepath.Join(dir, filename)
+		if _, err := os.Stat(path); err == nil && !isLegacyTemplate(path) {
+			return path, nil
 		}
 	}
 	return "", fmt.Errorf("template %s não encontrado", filename)
 }
 
-// loadDistroInfo lê o ficheiro templates/<distro>.ini e retorna as informações da distro
+// readTemplateFile lê um template dos diretórios de templates ou, em último caso,
+// dos ficheiros embutidos. Retorna o conteúdo e a origem (caminho ou "embutido").
+func readTemplateFile(filename string) ([]byte, string, error) {
+	if path, err := findTemplate(filename); err == nil {
+		data, err := os.ReadFile(path)
+		return data, path, err
+	}
+	data, err := fs.ReadFile(templates.FS, filename)
+	if err != nil {
+		return nil, "", fmt.Errorf("template %s não encontrado", filename)
+	}
+	return data, embeddedSource, nil
+}
+
+// loadDistroInfo lê o ficheiro <distro>.ini (local, do utilizador ou embutido) e retorna as informações da distro
 func loadDistroInfo(distro string) (*DistroInfo, error) {
-	iniPath, err := findTemplate(fmt.Sprintf("%s.ini", distro)) //filepath.Join("templates", fmt.Sprintf("%s.ini", distro))
-	cfg, err := ini.Load(iniPath)
+	data, _, err := readTemplateFile(fmt.Sprintf("%s.ini", distro))
+	if err != nil {
+		return nil, fmt.Errorf("distro '%s' desconhecida: %v", distro, err)
+	}
+	cfg, err := ini.Load(data)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao carregar INI da distro: %v", err)
 	}
+	maxAge, err := parseMaxAge(cfg.Section("").Key("MAX_AGE").String())
+	if err != nil {
+		return nil, err
+	}
+	firmware := strings.ToLower(cfg.Section("").Key("FIRMWARE").String())
+	if firmware != "" && firmware != "bios" && firmware != "uefi" {
+		return nil, fmt.Errorf("FIRMWARE inválido '%s' na distro '%s' (use bios ou uefi)", firmware, distro)
+	}
+	extraArgs, err := splitShellWords(cfg.Section("").Key("EXTRA_ARGS").String())
+	if err != nil {
+		return nil, fmt.Errorf("EXTRA_ARGS inválido na distro '%s': %v", distro, err)
+	}
 	return &DistroInfo{
-		URL:       cfg.Section("").Key("URL").String(),
-		Source:    cfg.Section("").Key("SOURCE").String(),
-		OSVariant: cfg.Section("").Key("OSVARIANT").String(),
+		URL:             cfg.Section("").Key("URL").String(),
+		Source:          cfg.Section("").Key("SOURCE").String(),
+		OSVariant:       cfg.Section("").Key("OSVARIANT").String(),
+		SHA256:          cfg.Section("").Key("SHA256").String(),
+		ChecksumURL:     cfg.Section("").Key("CHECKSUM_URL").String(),
+		Mirrors:         cfg.Section("").Key("MIRRORS").Strings(","),
+		MaxAge:          maxAge,
+		Console:         cfg.Section("").Key("CONSOLE").String(),
+		Firmware:        firmware,
+		Machine:         cfg.Section("").Key("MACHINE").String(),
+		Arch:            cfg.Section("").Key("ARCH").String(),
+		NICName:         cfg.Section("").Key("NIC_NAME").String(),
+		NetworkTemplate: cfg.Section("").Key("NETWORK_TEMPLATE").String(),
+		ExtraArgs:       extraArgs,
 	}, nil
 }
+
+// splitShellWords divide uma linha em argumentos como a shell: aspas simples e duplas
+// agrupam palavras com espaços e a barra invertida escapa o carácter seguinte
+func splitShellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("aspas ou escape por terminar: %s", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// listDistros retorna os nomes de todas as distros conhecidas: locais, do utilizador e embutidas
+func listDistros() []string {
+	seen := make(map[string]bool)
+	var distros []string
+	add := func(filename string) {
+		name := strings.TrimSuffix(filepath.Base(filename), ".ini")
+		if !seen[name] {
+			seen[name] = true
+			distros = append(distros, name)
+		}
+	}
+	for _, dir := range templateDirs() {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.ini"))
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	embedded, _ := fs.Glob(templates.FS, "*.ini")
+	for _, match := range embedded {
+		add(match)
+	}
+	sort.Strings(distros)
+	return distros
+}
+
+// distroOrigin retorna de onde vem a definição da distro: o caminho do INI ou "embutido"
+func distroOrigin(distro string) string {
+	_, origin, err := readTemplateFile(fmt.Sprintf("%s.ini", distro))
+	if err != nil {
+		return "-"
+	}
+	return origin
+}
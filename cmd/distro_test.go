@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  --noreboot   --cpu host ", []string{"--noreboot", "--cpu", "host"}},
+		{`--qemu-commandline="-foo bar"`, []string{"--qemu-commandline=-foo bar"}},
+		{`--qemu-commandline='-foo "bar"'`, []string{`--qemu-commandline=-foo "bar"`}},
+		{`a\ b c`, []string{"a b", "c"}},
+		{`'' x`, []string{"", "x"}},
+	}
+	for _, tt := range tests {
+		got, err := splitShellWords(tt.line)
+		if err != nil {
+			t.Fatalf("splitShellWords(%q): %v", tt.line, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitShellWords(%q) = %q, esperado %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{`"aberto`, `'aberto`, `fim\`} {
+		if _, err := splitShellWords(line); err == nil {
+			t.Errorf("splitShellWords(%q) sem erro", line)
+		}
+	}
+}
+
+func TestIsLegacyTemplate(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "meta-data.tmpl")
+	// meta-data.tmpl tal como o antigo INSTALL.sh o instalava (sem newline final)
+	old := "instance-id: {{.InstanceID}}\nlocal-hostname: {{.Hostname}}"
+	if err := os.WriteFile(legacy, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !isLegacyTemplate(legacy) {
+		t.Errorf("esperado template antigo em %s", legacy)
+	}
+
+	edited := filepath.Join(dir, "user-data.tmpl")
+	if err := os.WriteFile(edited, []byte("#cloud-config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isLegacyTemplate(edited) {
+		t.Errorf("template alterado não deve ser considerado antigo")
+	}
+	if isLegacyTemplate(filepath.Join(dir, "outro.tmpl")) {
+		t.Errorf("ficheiro desconhecido não deve ser considerado antigo")
+	}
+}
+
+func TestDistroAddRejectsInvalidNames(t *testing.T) {
+	kvm := &KVMCompose{}
+	opts := DistroAddOptions{URL: "https://exemplo.pt/imagem.qcow2", OSVariant: "generic", Local: true}
+	for _, name := range []string{"../x", "a/b", "Ubuntu", "", "..", "rocky 9"} {
+		if err := kvm.DistroAdd(name, opts); err == nil || !strings.Contains(err.Error(), "inválido") {
+			t.Errorf("DistroAdd(%q) = %v, esperado nome inválido", name, err)
+		}
+	}
+	for _, name := range []string{"ubuntu24.04", "almalinux10", "rocky-9"} {
+		if !distroNamePattern.MatchString(name) {
+			t.Errorf("nome %q rejeitado", name)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// DistroList lista as distros conhecidas com o URL, a variante e o estado da imagem em cache
+func (kvm *KVMCompose) DistroList() error {
+	color.Cyan("=== Distros disponíveis ===")
+	color.New(color.FgGreen, color.Bold).Printf("%-14s %-14s %-12s %-12s %s\n", "Distro", "Variante", "Origem", "Cache", "URL")
+	color.New(color.FgGreen, color.Bold).Printf("%-14s %-14s %-12s %-12s %s\n",
+		strings.Repeat("-", 14), strings.Repeat("-", 14), strings.Repeat("-", 12), strings.Repeat("-", 12), strings.Repeat("-", 40))
+
+	for _, distro := range listDistros() {
+		distroInfo, err := loadDistroInfo(distro)
+		if err != nil {
+			color.Red("%-14s %v", distro, err)
+			continue
+		}
+		origin := "utilizador"
+		switch o := distroOrigin(distro); {
+		case o == embeddedSource:
+			origin = embeddedSource
+		case !filepath.IsAbs(o):
+			origin = "local"
+		}
+		cache := "não baixada"
+		if info, err := os.Stat(kvm.getDistroImagePath(distro)); err == nil {
+			cache = formatSize(info.Size())
+		}
+		fmt.Printf("%-14s %-14s %-12s %-12s %s\n", distro, distroInfo.OSVariant, origin, cache, distroInfo.URL)
+	}
+	return nil
+}
+
+// DistroShow mostra a definição completa de uma distro e o estado da sua imagem em cache
+func (kvm *KVMCompose) DistroShow(distro string) error {
+	distroInfo, err := loadDistroInfo(distro)
+	if err != nil {
+		return err
+	}
+
+	color.Cyan("=== Distro %s ===", distro)
+	fmt.Printf("Origem: %s\n", distroOrigin(distro))
+	fmt.Printf("URL: %s\n", distroInfo.URL)
+	fmt.Printf("Ficheiro: %s\n", distroInfo.Source)
+	fmt.Printf("Variante (os-variant): %s\n", distroInfo.OSVariant)
+	if distroInfo.SHA256 != "" {
+		fmt.Printf("SHA256: %s\n", distroInfo.SHA256)
+	}
+	if distroInfo.ChecksumURL != "" {
+		fmt.Printf("Checksums: %s\n", distroInfo.ChecksumURL)
+	}
+	if len(distroInfo.Mirrors) > 0 {
+		fmt.Printf("Mirrors: %s\n", strings.Join(distroInfo.Mirrors, ", "))
+	}
+	if distroInfo.MaxAge > 0 {
+		fmt.Printf("Idade máxima: %s\n", distroInfo.MaxAge)
+	}
+	if distroInfo.Firmware != "" {
+		fmt.Printf("Firmware: %s\n", distroInfo.Firmware)
+	}
+	if distroInfo.Machine != "" {
+		fmt.Printf("Máquina: %s\n", distroInfo.Machine)
+	}
+	if distroInfo.Arch != "" {
+		fmt.Printf("Arquitetura: %s\n", distroInfo.Arch)
+	}
+	if distroInfo.Console != "" {
+		fmt.Printf("Consola: %s\n", distroInfo.Console)
+	}
+	fmt.Printf("Interface de rede: %s\n", distroInfo.nicName())
+	if distroInfo.NetworkTemplate != "" {
+		fmt.Printf("Template de rede: %s\n", distroInfo.NetworkTemplate)
+	}
+	if len(distroInfo.ExtraArgs) > 0 {
+		fmt.Printf("Argumentos extra: %s\n", strings.Join(distroInfo.ExtraArgs, " "))
+	}
+
+	imagePath := kvm.getDistroImagePath(distro)
+	fmt.Printf("Imagem em cache: %s", imagePath)
+	info, err := os.Stat(imagePath)
+	if err != nil {
+		color.Yellow(" (não baixada)")
+		return nil
+	}
+	fmt.Printf(" (%s)\n", formatSize(info.Size()))
+	meta := loadImageMeta(filepath.Dir(imagePath), distroInfo)
+	if !meta.DownloadedAt.IsZero() {
+		fmt.Printf("Baixada em: %s\n", meta.DownloadedAt.Format("2006-01-02 15:04"))
+	}
+	if meta.Checksum != "" {
+		fmt.Printf("Checksum: %s\n", meta.Checksum)
+	}
+	return nil
+}
+
+// distroNamePattern é o formato dos nomes de distro, igual ao dos INIs embutidos (ex.: ubuntu24.04)
+var distroNamePattern = regexp.MustCompile(`^[a-z0-9.-]+$`)
+
+// DistroAddOptions define os campos do INI de uma nova distro
+type DistroAddOptions struct {
+	URL         string
+	Source      string
+	OSVariant   string
+	SHA256      string
+	ChecksumURL string
+	Local       bool
+	Force       bool
+}
+
+// DistroAdd cria o INI de uma nova distro em ~/.config/kvm-compose/templates (ou em ./templates com Local)
+func (kvm *KVMCompose) DistroAdd(distro string, opts DistroAddOptions) error {
+	if !distroNamePattern.MatchString(distro) || strings.Trim(distro, ".") == "" {
+		return fmt.Errorf("nome de distro inválido '%s' (use apenas a-z, 0-9, '.' e '-')", distro)
+	}
+	if opts.URL == "" || opts.OSVariant == "" {
+		return fmt.Errorf("--url e --os-variant são obrigatórios")
+	}
+	if opts.Source == "" {
+		parsed, err := url.Parse(opts.URL)
+		if err != nil {
+			return fmt.Errorf("URL inválido '%s': %v", opts.URL, err)
+		}
+		opts.Source = path.Base(parsed.Path)
+	}
+
+	dir := localTemplateDir
+	if !opts.Local {
+		var err error
+		if dir, err = userTemplateDir(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+	iniPath := filepath.Join(dir, distro+".ini")
+	if _, err := os.Stat(iniPath); err == nil && !opts.Force {
+		return fmt.Errorf("%s já existe (use --force para substituir)", iniPath)
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "URL=%s\n", opts.URL)
+	fmt.Fprintf(&content, "SOURCE=%s\n", opts.Source)
+	fmt.Fprintf(&content, "OSVARIANT=%s\n", opts.OSVariant)
+	if opts.SHA256 != "" {
+		fmt.Fprintf(&content, "SHA256=%s\n", opts.SHA256)
+	}
+	if opts.ChecksumURL != "" {
+		fmt.Fprintf(&content, "CHECKSUM_URL=%s\n", opts.ChecksumURL)
+	}
+	if err := os.WriteFile(iniPath, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever %s: %v", iniPath, err)
+	}
+	color.Green("✅ Distro %s adicionada: %s", distro, iniPath)
+	return nil
+}
+
+var distroAddOptions DistroAddOptions
+
+var distroCmd = &cobra.Command{
+	Use:   "distro",
+	Short: "Gerir o catálogo de distros",
+}
+
+var distroListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "Listar as distros conhecidas",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.DistroList(); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var distroShowCmd = &cobra.Command{
+	Use:   "show <distro>",
+	Short: "Mostrar a definição de uma distro",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.DistroShow(args[0]); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var distroAddCmd = &cobra.Command{
+	Use:   "add <distro>",
+	Short: "Adicionar uma distro ao catálogo do utilizador",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.DistroAdd(args[0], distroAddOptions); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Register distro command
+	distroAddCmd.Flags().StringVar(&distroAddOptions.URL, "url", "", "URL da imagem cloud (obrigatório)")
+	distroAddCmd.Flags().StringVar(&distroAddOptions.OSVariant, "os-variant", "", "Variante do virt-install, ver 'osinfo-query os' (obrigatório)")
+	distroAddCmd.Flags().StringVar(&distroAddOptions.Source, "source", "", "Nome do ficheiro da imagem em cache (por omissão, o nome no URL)")
+	distroAddCmd.Flags().StringVar(&distroAddOptions.SHA256, "sha256", "", "SHA256 esperado da imagem")
+	distroAddCmd.Flags().StringVar(&distroAddOptions.ChecksumURL, "checksum-url", "", "URL do ficheiro de checksums (SHA256SUMS/SHA512SUMS)")
+	distroAddCmd.Flags().BoolVar(&distroAddOptions.Local, "local", false, "Escrever em ./templates em vez de ~/.config/kvm-compose/templates")
+	distroAddCmd.Flags().BoolVar(&distroAddOptions.Force, "force", false, "Substituir uma definição existente")
+	distroCmd.AddCommand(distroListCmd)
+	distroCmd.AddCommand(distroShowCmd)
+	distroCmd.AddCommand(distroAddCmd)
+	rootCmd.AddCommand(distroCmd)
+}
@@ -3,13 +3,19 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// DownOptions representa as opções do comando down
+type DownOptions struct {
+	Volumes bool
+}
+
 // Down destrói todas as VMs
-func (kvm *KVMCompose) Down() error {
+func (kvm *KVMCompose) Down(opts DownOptions) error {
 	err := kvm.loadConfig()
 	if err != nil {
 		return err
@@ -19,6 +25,7 @@ func (kvm *KVMCompose) Down() error {
 
 	destroyedCount := 0
 	missingCount := 0
+	failedCount := 0
 
 	for _, vm := range kvm.config.VMs {
 		color.White("--- Destruindo VM: %s ---", vm.Name)
@@ -34,40 +41,115 @@ func (kvm *KVMCompose) Down() error {
 				execCommand("virsh", "destroy", vm.Name)
 			}
 
-			// Remover VM
-			if err := execCommand("virsh", "undefine", vm.Name); err != nil {
-				color.Red("❌ Falha ao remover VM %s do libvirt: %v", vm.Name, err)
-			} else {
-				color.Green("✅ VM %s removida do libvirt", vm.Name)
-				destroyedCount++
+			// Remover VM (o libvirt recusa remover um domínio com metadados de snapshots
+			// ou, com FIRMWARE=uefi, com NVRAM)
+			if err := execCommand("virsh", "undefine", vm.Name, "--snapshots-metadata", "--nvram"); err != nil {
+				// Os discos continuam em uso pelo domínio e pelos snapshots: não remover nada
+				color.Red("❌ Falha ao remover VM %s do libvirt: %v (discos mantidos)", vm.Name, err)
+				failedCount++
+				fmt.Println()
+				continue
 			}
+			color.Green("✅ VM %s removida do libvirt", vm.Name)
+			destroyedCount++
 		}
 
 		// Remover arquivo de disco
 		vmImagePath := kvm.getVMImagePath(vm.Name)
-		if _, err := os.Stat(vmImagePath); err == nil {
-			os.Remove(vmImagePath)
+		if kvm.removeDisk(vmImagePath) {
 			color.Blue("💾 Arquivo de disco %s removido", vmImagePath)
 		}
+
+		// Remover seed cloud-init
+		seedPath := kvm.getSeedPath(vm.Name)
+		if kvm.removeDisk(seedPath) {
+			color.Blue("💿 Seed cloud-init %s removido", seedPath)
+		}
+
+		// Remover diretório de trabalho da VM, exceto o log da consola (kvm-compose logs)
+		workDir := kvm.vmWorkDirPath(vm.Name)
+		if removeWorkDir(workDir) {
+			color.Blue("📁 Diretório de trabalho %s removido", workDir)
+		}
+
+		// Remover discos de dados (os volumes nomeados só com --volumes)
+		disks, err := kvm.resolveDataDisks(&vm)
+		if err != nil {
+			color.Yellow("⚠️  %v", err)
+		}
+		for _, d := range disks {
+			if d.Volume != "" {
+				continue
+			}
+			if kvm.removeDisk(d.Path) {
+				color.Blue("💾 Disco de dados %s removido", d.Path)
+			}
+		}
+		fmt.Println()
+	}
+
+	if opts.Volumes && failedCount > 0 {
+		color.Yellow("⚠️  Volumes nomeados mantidos: há VMs que não foram removidas")
+		fmt.Println()
+	} else if opts.Volumes {
+		for name, volume := range kvm.config.Volumes {
+			volumePath := kvm.getVolumePath(name, volume.Format)
+			if kvm.removeDisk(volumePath) {
+				color.Blue("🗑️  Volume %s removido (%s)", name, volumePath)
+			}
+		}
 		fmt.Println()
 	}
 
 	color.Cyan("=== Resumo ===")
 	fmt.Printf("VMs destruídas: %d\n", destroyedCount)
 	fmt.Printf("VMs não existiam: %d\n", missingCount)
+	if failedCount > 0 {
+		fmt.Printf("VMs não removidas: %d\n", failedCount)
+	}
 	fmt.Printf("Total de VMs no compose: %d\n", len(kvm.config.VMs))
 
+	if failedCount > 0 {
+		return fmt.Errorf("%d VM(s) não foram removidas do libvirt", failedCount)
+	}
 	return nil
 }
 
+// removeWorkDir remove o diretório de trabalho de uma VM, mantendo o log da consola série
+// para que continue disponível após o down. Retorna true se algo foi removido.
+func removeWorkDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	removed := false
+	for _, entry := range entries {
+		if entry.Name() == consoleLogName {
+			continue
+		}
+		if os.RemoveAll(filepath.Join(dir, entry.Name())) == nil {
+			removed = true
+		}
+	}
+	// Só fica vazio se não houver log da consola
+	os.Remove(dir)
+	return removed
+}
+
+var downOptions DownOptions
+
 var downCmd = &cobra.Command{
 	Use:   "down",
 	Short: "Destruir todas as VMs do compose",
 	Run: func(cmd *cobra.Command, args []string) {
 		kvm := NewKVMCompose(composeFile)
-		if err := kvm.Down(); err != nil {
+		if err := kvm.Down(downOptions); err != nil {
 			color.Red("Erro: %v", err)
 			os.Exit(1)
 		}
 	},
 }
+
+func init() {
+	downCmd.Flags().BoolVar(&downOptions.Volumes, "volumes", false, "Remover também os volumes nomeados do projeto")
+}
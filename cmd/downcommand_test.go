@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveWorkDirKeepsConsoleLog(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "web")
+	os.MkdirAll(dir, 0755)
+	for _, name := range []string{"user-data", "meta-data", consoleLogName} {
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+
+	if !removeWorkDir(dir) {
+		t.Error("esperado remoção dos ficheiros cloud-init")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != consoleLogName {
+		t.Errorf("ficheiros restantes %v, esperado só %s", entries, consoleLogName)
+	}
+
+	// Sem log da consola, o diretório é removido
+	os.Remove(filepath.Join(dir, consoleLogName))
+	os.WriteFile(filepath.Join(dir, "user-data"), []byte("x"), 0644)
+	removeWorkDir(dir)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("diretório de trabalho vazio não foi removido")
+	}
+	if removeWorkDir(dir) {
+		t.Error("diretório inexistente reportado como removido")
+	}
+}
@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// downloadRetries é o número de tentativas por URL
+	downloadRetries = 4
+	// fetchTimeout é o tempo máximo para baixar ficheiros pequenos (ex.: checksums)
+	fetchTimeout = 2 * time.Minute
+	// partialSuffix é a extensão das imagens ainda a ser baixadas
+	partialSuffix = ".part"
+)
+
+var (
+	// downloadBackoff é a espera antes da segunda tentativa (duplica a cada falha)
+	downloadBackoff = 2 * time.Second
+	// downloadIdleTimeout é o tempo máximo sem receber dados antes de abortar a tentativa
+	downloadIdleTimeout = 60 * time.Second
+)
+
+// httpClient usa o proxy definido no ambiente (HTTP_PROXY, HTTPS_PROXY, NO_PROXY).
+// Não tem um timeout global: os downloads grandes são abortados quando ficam parados.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   30 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	},
+}
+
+// remoteVersion identifica a versão de um ficheiro remoto pelos cabeçalhos HTTP
+type remoteVersion struct {
+	URL          string `json:"url,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ifRange retorna o validador a enviar em If-Range: o ETag forte ou, sem ele, o Last-Modified
+func (v *remoteVersion) ifRange() string {
+	if v.ETag != "" && !strings.HasPrefix(v.ETag, "W/") {
+		return v.ETag
+	}
+	return v.LastModified
+}
+
+// loadPartialVersion lê a versão remota de que veio um download parcial (nil se desconhecida)
+func loadPartialVersion(dest string) *remoteVersion {
+	data, err := os.ReadFile(dest + imageMetaSuffix)
+	if err != nil {
+		return nil
+	}
+	version := &remoteVersion{}
+	if err := json.Unmarshal(data, version); err != nil {
+		return nil
+	}
+	return version
+}
+
+// savePartialVersion regista a versão remota de um download parcial, para o retomar com segurança
+func savePartialVersion(dest string, version *remoteVersion) error {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest+imageMetaSuffix, data, 0644)
+}
+
+// idleTimeoutReader cancela o pedido quando o corpo da resposta fica parado
+type idleTimeoutReader struct {
+	r     io.Reader
+	timer *time.Timer
+	idle  time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.timer.Reset(r.idle)
+	return n, err
+}
+
+// downloadFile baixa o primeiro URL disponível (http, https ou file) para dest.
+// Um dest parcial de uma tentativa anterior é retomado com pedidos Range condicionais
+// (If-Range): se o ficheiro remoto mudou entretanto, o download recomeça do início.
+func downloadFile(urls []string, dest string) (*remoteVersion, error) {
+	var lastErr error
+	for i, rawURL := range urls {
+		if i > 0 {
+			color.Yellow("🔁 Tentando mirror: %s", rawURL)
+		}
+		version, err := downloadWithRetry(rawURL, dest)
+		if err == nil {
+			os.Remove(dest + imageMetaSuffix)
+			return version, nil
+		}
+		lastErr = err
+		color.Yellow("⚠️  Falha ao baixar %s: %v", rawURL, lastErr)
+	}
+	return nil, lastErr
+}
+
+// downloadWithRetry baixa um URL com novas tentativas e espera exponencial
+func downloadWithRetry(rawURL, dest string) (*remoteVersion, error) {
+	wait := downloadBackoff
+	var err error
+	for attempt := 1; attempt <= downloadRetries; attempt++ {
+		version := &remoteVersion{}
+		if err = downloadOnce(rawURL, dest, version); err == nil {
+			return version, nil
+		}
+		if attempt < downloadRetries {
+			color.Yellow("⚠️  Tentativa %d/%d falhou: %v (nova tentativa em %s)", attempt, downloadRetries, err, wait)
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	return nil, err
+}
+
+// contentRangeSize retorna o tamanho total indicado num Content-Range "bytes */N" (resposta 416)
+func contentRangeSize(header string) (int64, bool) {
+	total, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes */")
+	if !ok {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	return size, err == nil
+}
+
+// downloadOnce faz uma tentativa de download, retomando a partir do tamanho atual de dest,
+// e regista em version os cabeçalhos ETag e Last-Modified da resposta
+func downloadOnce(rawURL, dest string, version *remoteVersion) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	// Só se retoma um parcial cuja origem (URL e versão) é conhecida
+	var offset int64
+	if info, err := os.Stat(dest); err == nil {
+		offset = info.Size()
+	}
+	partial := loadPartialVersion(dest)
+	if offset > 0 && (partial == nil || partial.URL != rawURL || partial.ifRange() == "") {
+		color.Yellow("⚠️  Download parcial de origem desconhecida, recomeçando do início")
+		offset = 0
+	}
+	version.URL = rawURL
+
+	if parsed.Scheme == "file" {
+		if info, err := os.Stat(parsed.Path); err == nil {
+			version.LastModified = info.ModTime().UTC().Format(http.TimeFormat)
+		}
+		if offset > 0 && partial.LastModified != version.LastModified {
+			offset = 0
+		}
+		if err := savePartialVersion(dest, version); err != nil {
+			return err
+		}
+		return copyLocalFile(parsed.Path, dest, offset)
+	}
+
+	// O pedido é cancelado se não chegarem dados durante downloadIdleTimeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := time.AfterFunc(downloadIdleTimeout, cancel)
+	defer timer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", partial.ifRange())
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	version.ETag = resp.Header.Get("ETag")
+	version.LastModified = resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusPartialContent && version.ETag == "" && version.LastModified == "" {
+		// Alguns servidores não repetem os validadores na resposta 206
+		version.ETag, version.LastModified = partial.ETag, partial.LastModified
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		color.Cyan("⏩ Retomando download a partir de %s", formatSize(offset))
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Sem Range ou com If-Range falhado (o ficheiro remoto mudou): recomeçar do início
+		if offset > 0 {
+			color.Yellow("⚠️  O ficheiro remoto mudou desde o download parcial, recomeçando do início")
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+		if err := savePartialVersion(dest, version); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// O ficheiro parcial só está completo se tiver exatamente o tamanho remoto
+		if size, ok := contentRangeSize(resp.Header.Get("Content-Range")); ok && size == offset {
+			version.ETag, version.LastModified = partial.ETag, partial.LastModified
+			return nil
+		}
+		color.Yellow("⚠️  Download parcial não corresponde ao ficheiro remoto, recomeçando do início")
+		resp.Body.Close()
+		if err := os.Remove(dest); err != nil {
+			return err
+		}
+		os.Remove(dest + imageMetaSuffix)
+		return downloadOnce(rawURL, dest, version)
+	default:
+		return fmt.Errorf("resposta HTTP %s", resp.Status)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	out, err := os.OpenFile(dest, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	body := &idleTimeoutReader{r: resp.Body, timer: timer, idle: downloadIdleTimeout}
+	if err := copyWithProgress(out, body, offset, total); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("download parado há mais de %s", downloadIdleTimeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// copyLocalFile copia um ficheiro local (URLs file://) para dest, retomando a partir de offset
+func copyLocalFile(src, dest string, offset int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if offset > info.Size() {
+		offset = 0
+	}
+	if _, err := in.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := out.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return copyWithProgress(out, in, offset, info.Size())
+}
+
+// copyWithProgress copia os dados mostrando uma barra de progresso
+func copyWithProgress(dst io.Writer, src io.Reader, done, total int64) error {
+	start := time.Now()
+	startDone := done
+	lastDraw := time.Time{}
+	buf := make([]byte, 256*1024)
+
+	draw := func(final bool) {
+		if !final && time.Since(lastDraw) < 200*time.Millisecond {
+			return
+		}
+		lastDraw = time.Now()
+		speed := float64(done-startDone) / time.Since(start).Seconds()
+		if total > 0 {
+			const width = 30
+			filled := int(float64(width) * float64(done) / float64(total))
+			fmt.Fprintf(os.Stderr, "\r   %3d%% [%s%s] %s/%s %s/s   ",
+				done*100/total, strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+				formatSize(done), formatSize(total), formatSize(int64(speed)))
+		} else {
+			fmt.Fprintf(os.Stderr, "\r   %s %s/s   ", formatSize(done), formatSize(int64(speed)))
+		}
+		if final {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				draw(true)
+				return err
+			}
+			done += int64(n)
+			draw(false)
+		}
+		if readErr == io.EOF {
+			draw(true)
+			if total > 0 && done < total {
+				return fmt.Errorf("download incompleto: %s de %s", formatSize(done), formatSize(total))
+			}
+			return nil
+		}
+		if readErr != nil {
+			draw(true)
+			return readErr
+		}
+	}
+}
+
+// remoteChanged verifica com um pedido HEAD condicional (If-None-Match/If-Modified-Since)
+// se o ficheiro remoto mudou desde a versão conhecida
+func remoteChanged(rawURL string, known remoteVersion) (bool, *remoteVersion, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false, nil, err
+	}
+	current := &remoteVersion{}
+	if parsed.Scheme == "file" {
+		info, err := os.Stat(parsed.Path)
+		if err != nil {
+			return false, nil, err
+		}
+		current.LastModified = info.ModTime().UTC().Format(http.TimeFormat)
+		return current.LastModified != known.LastModified, current, nil
+	}
+
+	req, err := http.NewRequest(http.MethodHead, rawURL, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	if known.ETag != "" {
+		req.Header.Set("If-None-Match", known.ETag)
+	}
+	if known.LastModified != "" {
+		req.Header.Set("If-Modified-Since", known.LastModified)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, &known, nil
+	case http.StatusOK:
+	default:
+		return false, nil, fmt.Errorf("resposta HTTP %s", resp.Status)
+	}
+
+	// Alguns servidores ignoram os cabeçalhos condicionais
+	current.ETag = resp.Header.Get("ETag")
+	current.LastModified = resp.Header.Get("Last-Modified")
+	switch {
+	case current.ETag != "" && known.ETag != "":
+		return current.ETag != known.ETag, current, nil
+	case current.LastModified != "" && known.LastModified != "":
+		return current.LastModified != known.LastModified, current, nil
+	}
+	return true, current, nil
+}
+
+// fetchURL baixa um URL pequeno (ex.: ficheiro de checksums) para memória
+func fetchURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme == "file" {
+		data, err := os.ReadFile(parsed.Path)
+		return string(data), err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resposta HTTP %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quietDownloads reduz as esperas entre tentativas durante o teste
+func quietDownloads(t *testing.T) {
+	t.Helper()
+	backoff, idle := downloadBackoff, downloadIdleTimeout
+	downloadBackoff, downloadIdleTimeout = time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() { downloadBackoff, downloadIdleTimeout = backoff, idle })
+}
+
+// imageServer serve content com ETag e suporte a Range/If-Range (http.ServeContent)
+func imageServer(t *testing.T, content *[]byte, etag *string, requests *[]*http.Request) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			*requests = append(*requests, r.Clone(r.Context()))
+		}
+		w.Header().Set("ETag", *etag)
+		http.ServeContent(w, r, "image.qcow2", time.Time{}, bytes.NewReader(*content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadFileResumesUnchangedFile(t *testing.T) {
+	quietDownloads(t)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	etag := `"v1"`
+	var requests []*http.Request
+	server := imageServer(t, &content, &etag, &requests)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	// Parcial de uma tentativa anterior, com a versão registada
+	os.WriteFile(dest, content[:4000], 0644)
+	savePartialVersion(dest, &remoteVersion{URL: server.URL, ETag: etag})
+
+	version, err := downloadFile([]string{server.URL}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("conteúdo diferente após retomar (%d bytes)", len(got))
+	}
+	if r := requests[0]; r.Header.Get("Range") != "bytes=4000-" || r.Header.Get("If-Range") != etag {
+		t.Errorf("cabeçalhos Range=%q If-Range=%q", r.Header.Get("Range"), r.Header.Get("If-Range"))
+	}
+	if version.ETag != etag {
+		t.Errorf("ETag %q, esperado %q", version.ETag, etag)
+	}
+	if _, err := os.Stat(dest + imageMetaSuffix); !os.IsNotExist(err) {
+		t.Error("versão do parcial não foi removida após o download")
+	}
+}
+
+func TestDownloadFileRestartsWhenRemoteChanged(t *testing.T) {
+	quietDownloads(t)
+	old := bytes.Repeat([]byte("a"), 5000)
+	content := bytes.Repeat([]byte("b"), 8000)
+	etag := `"v2"`
+	server := imageServer(t, &content, &etag, nil)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	// O parcial veio da versão anterior: If-Range falha e o servidor envia tudo (200)
+	os.WriteFile(dest, old[:3000], 0644)
+	savePartialVersion(dest, &remoteVersion{URL: server.URL, ETag: `"v1"`})
+
+	if _, err := downloadFile([]string{server.URL}, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("bytes antigos misturados com os novos (%d bytes)", len(got))
+	}
+}
+
+func TestDownloadFileRestartsUnknownPartial(t *testing.T) {
+	quietDownloads(t)
+	content := bytes.Repeat([]byte("c"), 6000)
+	etag := `"v1"`
+	var requests []*http.Request
+	server := imageServer(t, &content, &etag, &requests)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	// Parcial sem versão registada (ex.: de uma versão anterior do kvm-compose)
+	os.WriteFile(dest, []byte("lixo"), 0644)
+
+	if _, err := downloadFile([]string{server.URL}, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("conteúdo diferente (%d bytes)", len(got))
+	}
+	if r := requests[0]; r.Header.Get("Range") != "" {
+		t.Errorf("parcial desconhecido retomado com Range=%q", r.Header.Get("Range"))
+	}
+}
+
+func TestDownloadOnceAbortsStalledBody(t *testing.T) {
+	quietDownloads(t)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte(strings.Repeat("x", 100)))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+	done := make(chan error, 1)
+	go func() { done <- downloadOnce(server.URL, dest, &remoteVersion{}) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "parado") {
+			t.Errorf("erro inesperado: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("download parado não foi abortado")
+	}
+}
+
+func TestDownloadFileFallsBackToMirror(t *testing.T) {
+	quietDownloads(t)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "indisponível", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	content := bytes.Repeat([]byte("m"), 3000)
+	etag := `"mirror"`
+	mirror := imageServer(t, &content, &etag, nil)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	version, err := downloadFile([]string{broken.URL, mirror.URL}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("conteúdo diferente (%d bytes)", len(got))
+	}
+	if version.URL != mirror.URL || version.ETag != etag {
+		t.Errorf("versão %+v, esperado o mirror", version)
+	}
+
+	if _, err := downloadFile([]string{broken.URL}, filepath.Join(t.TempDir(), "x.part")); err == nil {
+		t.Error("esperado erro sem nenhum URL disponível")
+	}
+}
+
+func TestRemoteChanged(t *testing.T) {
+	content := []byte("imagem")
+	etag := `"v1"`
+	server := imageServer(t, &content, &etag, nil)
+
+	changed, _, err := remoteChanged(server.URL, remoteVersion{ETag: `"v1"`})
+	if err != nil || changed {
+		t.Errorf("mesmo ETag: changed=%v err=%v", changed, err)
+	}
+	etag = `"v2"`
+	changed, current, err := remoteChanged(server.URL, remoteVersion{ETag: `"v1"`})
+	if err != nil || !changed || current.ETag != `"v2"` {
+		t.Errorf("ETag novo: changed=%v current=%+v err=%v", changed, current, err)
+	}
+}
+
+func TestDownloadFileCompletePartial(t *testing.T) {
+	quietDownloads(t)
+	content := bytes.Repeat([]byte("d"), 4000)
+	etag := `"v1"`
+	server := imageServer(t, &content, &etag, nil)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	// O parcial já tem o ficheiro todo: o servidor responde 416 ao Range
+	os.WriteFile(dest, content, 0644)
+	savePartialVersion(dest, &remoteVersion{URL: server.URL, ETag: etag, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"})
+
+	version, err := downloadFile([]string{server.URL}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.ETag != etag || version.LastModified == "" {
+		t.Errorf("versão %+v, esperado a do parcial", version)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("conteúdo diferente (%d bytes)", len(got))
+	}
+}
+
+func TestDownloadFileRestartsOversizedPartial(t *testing.T) {
+	quietDownloads(t)
+	content := bytes.Repeat([]byte("e"), 3000)
+	etag := `"v1"`
+	server := imageServer(t, &content, &etag, nil)
+	dest := filepath.Join(t.TempDir(), "image.qcow2.part")
+
+	// Parcial maior do que o ficheiro remoto: 416, mas não está completo
+	os.WriteFile(dest, bytes.Repeat([]byte("x"), 5000), 0644)
+	savePartialVersion(dest, &remoteVersion{URL: server.URL, ETag: etag})
+
+	if _, err := downloadFile([]string{server.URL}, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Fatalf("parcial maior aceite como completo (%d bytes)", len(got))
+	}
+}
+
+func TestContentRangeSize(t *testing.T) {
+	tests := []struct {
+		header string
+		size   int64
+		ok     bool
+	}{
+		{"bytes */4000", 4000, true},
+		{" bytes */0", 0, true},
+		{"bytes 0-99/4000", 0, false},
+		{"bytes */*", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		if size, ok := contentRangeSize(tt.header); size != tt.size || ok != tt.ok {
+			t.Errorf("contentRangeSize(%q) = %d, %v; esperado %d, %v", tt.header, size, ok, tt.size, tt.ok)
+		}
+	}
+}
+
+func TestImageMetaVersionURL(t *testing.T) {
+	distroInfo := &DistroInfo{URL: "https://principal/img.qcow2", Mirrors: []string{"https://mirror/img.qcow2"}}
+	tests := []struct {
+		source, want string
+	}{
+		{"", distroInfo.URL},
+		{"https://mirror/img.qcow2", "https://mirror/img.qcow2"},
+		{"https://principal/img.qcow2", distroInfo.URL},
+		// Mirror retirado do INI: volta-se ao URL da distro
+		{"https://antigo/img.qcow2", distroInfo.URL},
+	}
+	for _, tt := range tests {
+		meta := &imageMeta{SourceURL: tt.source}
+		if got := meta.versionURL(distroInfo); got != tt.want {
+			t.Errorf("versionURL(%q) = %q, esperado %q", tt.source, got, tt.want)
+		}
+	}
+}
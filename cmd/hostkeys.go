@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// hostKeysDirName guarda as chaves de host das VMs; sobrevive ao down para que as
+	// chaves (e o known_hosts) se mantenham entre recriações
+	hostKeysDirName = "hostkeys"
+	// knownHostsName é o known_hosts do projeto, usado pelo comando ssh
+	knownHostsName = "known_hosts"
+)
+
+// hostKeyTypes são os tipos de chave de host gerados, com os nomes usados pelo cloud-init
+var hostKeyTypes = []string{"ed25519", "ecdsa"}
+
+// hostKey é uma chave de host SSH de uma VM
+type hostKey struct {
+	Type    string
+	Private string
+	Public  string
+}
+
+// knownHostsPath retorna o caminho do known_hosts do projeto
+func (kvm *KVMCompose) knownHostsPath() string {
+	return filepath.Join(kvm.projectDir(), knownHostsName)
+}
+
+// hostKeyPath retorna o caminho da chave de host privada de um tipo para a VM
+func (kvm *KVMCompose) hostKeyPath(vmName, keyType string) string {
+	return filepath.Join(kvm.projectDir(), hostKeysDirName, vmName, fmt.Sprintf("ssh_host_%s_key", keyType))
+}
+
+// vmHostKeys retorna as chaves de host da VM. Com create, as que faltarem são geradas;
+// sem create, retorna nil se alguma não existir.
+func (kvm *KVMCompose) vmHostKeys(vmName string, create bool) ([]hostKey, error) {
+	var keys []hostKey
+	for _, keyType := range hostKeyTypes {
+		path := kvm.hostKeyPath(vmName, keyType)
+		private, errPriv := os.ReadFile(path)
+		public, errPub := os.ReadFile(path + ".pub")
+		if errPriv != nil || errPub != nil {
+			if !create {
+				return nil, nil
+			}
+			var err error
+			if private, public, err = generateHostKey(keyType, "root@"+vmName); err != nil {
+				return nil, fmt.Errorf("erro ao gerar chave de host %s: %v", keyType, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(path, private, 0600); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(path+".pub", public, 0644); err != nil {
+				return nil, err
+			}
+			color.Green("🔐 Chave de host %s gerada para %s", keyType, vmName)
+		}
+		keys = append(keys, hostKey{Type: keyType, Private: string(private), Public: strings.TrimSpace(string(public))})
+	}
+	return keys, nil
+}
+
+// generateHostKey gera uma chave de host do tipo indicado: a privada em PEM e a pública no formato OpenSSH
+func generateHostKey(keyType, comment string) ([]byte, []byte, error) {
+	switch keyType {
+	case "ed25519":
+		private, public, err := generateSSHKey(comment)
+		return private, []byte(public + "\n"), err
+	case "ecdsa":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		point, err := key.PublicKey.ECDH()
+		if err != nil {
+			return nil, nil, err
+		}
+		wire := sshString(nil, []byte("ecdsa-sha2-nistp256"))
+		wire = sshString(wire, []byte("nistp256"))
+		wire = sshString(wire, point.Bytes())
+		public := fmt.Sprintf("ecdsa-sha2-nistp256 %s %s\n", base64.StdEncoding.EncodeToString(wire), comment)
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), []byte(public), nil
+	}
+	return nil, nil, fmt.Errorf("tipo de chave de host desconhecido: %s", keyType)
+}
+
+// hostKeysCloudConfig gera as chaves cloud-init que instalam as chaves de host e
+// impedem o cloud-init de gerar outras
+func hostKeysCloudConfig(keys []hostKey) map[string]interface{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	sshKeys := map[string]interface{}{}
+	for _, key := range keys {
+		sshKeys[key.Type+"_private"] = key.Private
+		sshKeys[key.Type+"_public"] = key.Public
+	}
+	return map[string]interface{}{
+		"ssh_keys":        sshKeys,
+		"ssh_deletekeys":  true,
+		"ssh_genkeytypes": []interface{}{},
+	}
+}
+
+// updateKnownHosts regrava o known_hosts do projeto com as chaves de host de todas as VMs,
+// associadas ao nome e aos IPs de cada uma
+func (kvm *KVMCompose) updateKnownHosts() error {
+	var lines []string
+	for _, vm := range kvm.config.VMs {
+		keys, err := kvm.vmHostKeys(vm.Name, false)
+		if err != nil || len(keys) == 0 {
+			continue
+		}
+		hosts := []string{vm.Name}
+		for _, nic := range vm.Networks {
+			if nic.GuestIPv4 != "" {
+				hosts = append(hosts, nic.GuestIPv4)
+			}
+		}
+		for _, key := range keys {
+			fields := strings.Fields(key.Public)
+			if len(fields) < 2 {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s %s %s", strings.Join(hosts, ","), fields[0], fields[1]))
+		}
+	}
+	sort.Strings(lines)
+	if _, err := kvm.ensureProjectDir(); err != nil {
+		return err
+	}
+	return os.WriteFile(kvm.knownHostsPath(), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// knownHostsHas indica se o ficheiro known_hosts tem uma entrada para o host
+func knownHostsHas(path, host string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, name := range strings.Split(fields[0], ",") {
+			if name == host {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKnownHostsHas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), knownHostsName)
+	content := "# comentário\nweb,10.0.0.5 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA\ndb ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for host, want := range map[string]bool{"10.0.0.5": true, "web": true, "db": true, "10.0.0.50": false, "10.0.0": false} {
+		if got := knownHostsHas(path, host); got != want {
+			t.Errorf("knownHostsHas(%q) = %v, esperado %v", host, got, want)
+		}
+	}
+	if knownHostsHas(filepath.Join(t.TempDir(), "nao-existe"), "web") {
+		t.Error("known_hosts inexistente tem entradas")
+	}
+}
+
+func TestVMWorkDirKeepsProjectState(t *testing.T) {
+	kvm := &KVMCompose{composeFile: filepath.Join(t.TempDir(), "kvm-compose.yaml")}
+	state := []string{
+		filepath.Dir(filepath.Dir(kvm.hostKeyPath("web", "ed25519"))),
+		kvm.knownHostsPath(),
+		kvm.ipamStatePath(),
+		kvm.projectKeyPath(),
+	}
+	// Nenhum nome de VM pode apontar o diretório de trabalho para o estado do projeto
+	for _, name := range []string{hostKeysDirName, knownHostsName, "ipam.json", projectKeyName} {
+		dir := kvm.vmWorkDirPath(name)
+		for _, path := range state {
+			if dir == path {
+				t.Errorf("diretório de trabalho da VM %q coincide com %s", name, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// imageMetaSuffix é a extensão dos ficheiros de metadados das imagens em cache
+const imageMetaSuffix = ".meta.json"
+
+// imageMeta representa os metadados do download da imagem de uma distro,
+// gravados ao lado da imagem em <SOURCE>.meta.json
+type imageMeta struct {
+	File         string    `json:"file"`
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Checksum     string    `json:"checksum,omitempty"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	CheckedAt    time.Time `json:"checked_at"`
+	// SourceURL é o URL (o da distro ou um mirror) de onde veio o download, e
+	// a que pertencem ETag e LastModified
+	SourceURL string `json:"source_url,omitempty"`
+	// VerifiedSize e VerifiedModTime identificam o ficheiro cujo checksum já foi
+	// verificado, para não voltar a ler imagens de vários GB em cada up
+	VerifiedSize    int64     `json:"verified_size,omitempty"`
+	VerifiedModTime time.Time `json:"verified_mtime,omitempty"`
+
+	path string
+}
+
+// loadImageMeta lê os metadados da imagem de uma distro (vazios se ainda não existirem)
+func loadImageMeta(upstreamDir string, distroInfo *DistroInfo) *imageMeta {
+	meta := &imageMeta{path: filepath.Join(upstreamDir, distroInfo.Source+imageMetaSuffix)}
+	if data, err := os.ReadFile(meta.path); err == nil {
+		if err := json.Unmarshal(data, meta); err != nil {
+			color.Yellow("⚠️  Metadados inválidos em %s: %v", meta.path, err)
+		}
+	}
+	return meta
+}
+
+// save grava os metadados da imagem
+func (meta *imageMeta) save() error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(meta.path, append(data, '\n'), 0644)
+}
+
+// markVerified regista o tamanho e a data de modificação da imagem cujo checksum foi verificado
+func (meta *imageMeta) markVerified(imagePath string) {
+	if info, err := os.Stat(imagePath); err == nil {
+		meta.VerifiedSize, meta.VerifiedModTime = info.Size(), info.ModTime().UTC()
+	}
+}
+
+// alreadyVerified indica se a imagem não mudou desde a última verificação do checksum
+func (meta *imageMeta) alreadyVerified(imagePath string) bool {
+	info, err := os.Stat(imagePath)
+	return err == nil && meta.VerifiedSize > 0 && info.Size() == meta.VerifiedSize &&
+		info.ModTime().Equal(meta.VerifiedModTime)
+}
+
+// versionURL retorna o URL onde verificar a versão da imagem: aquele de onde foi baixada,
+// se ainda for o da distro ou um dos seus mirrors, ou o URL da distro
+func (meta *imageMeta) versionURL(distroInfo *DistroInfo) string {
+	for _, candidate := range append([]string{distroInfo.URL}, distroInfo.Mirrors...) {
+		if meta.SourceURL != "" && candidate == meta.SourceURL {
+			return candidate
+		}
+	}
+	return distroInfo.URL
+}
+
+// expired indica se já passou o MAX_AGE desde a última verificação de versão nova
+func (meta *imageMeta) expired(maxAge time.Duration) bool {
+	return maxAge > 0 && time.Since(meta.CheckedAt) > maxAge
+}
+
+// parseMaxAge interpreta o MAX_AGE do INI da distro (ex.: 12h, 7d)
+func parseMaxAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("MAX_AGE inválido '%s'", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("MAX_AGE inválido '%s'", value)
+	}
+	return d, nil
+}
+
+// refreshDistroImage baixa a imagem da distro novamente se a versão remota mudou.
+// A imagem anterior é mantida enquanto houver discos de VMs que a usem como backing file.
+func (kvm *KVMCompose) refreshDistroImage(distro string, distroInfo *DistroInfo, meta *imageMeta, currentPath string) error {
+	color.Cyan("🔄 Verificando se há uma versão nova da imagem da distro %s...", distro)
+	changed, _, err := remoteChanged(meta.versionURL(distroInfo), remoteVersion{ETag: meta.ETag, LastModified: meta.LastModified})
+	if err != nil {
+		color.Yellow("⚠️  Não foi possível verificar a versão remota: %v", err)
+		return verifyCachedImage(distro, distroInfo, meta, currentPath)
+	}
+	if !changed {
+		meta.CheckedAt = time.Now()
+		if meta.File == "" {
+			meta.File = filepath.Base(currentPath)
+		}
+		if err := meta.save(); err != nil {
+			return err
+		}
+		color.Green("✅ Imagem da distro %s já está atualizada", distro)
+		return verifyCachedImage(distro, distroInfo, meta, currentPath)
+	}
+
+	// A nova versão substitui o ficheiro apenas se nenhum disco depender dele
+	upstreamDir := filepath.Dir(currentPath)
+	users := kvm.baseImageUsers()
+	target := filepath.Join(upstreamDir, distroInfo.Source)
+	if len(users[target]) > 0 {
+		ext := filepath.Ext(distroInfo.Source)
+		stem := strings.TrimSuffix(distroInfo.Source, ext)
+		target = filepath.Join(upstreamDir, fmt.Sprintf("%s-%s%s", stem, time.Now().Format("20060102150405"), ext))
+	}
+	if target == currentPath && kvm.usePool() {
+		// O volume base no pool tem de ser enviado novamente
+		kvm.removeBaseVolume(filepath.Base(target))
+	}
+
+	if err := kvm.fetchDistroImage(distro, distroInfo, meta, target); err != nil {
+		return err
+	}
+
+	if target != currentPath {
+		if len(users[currentPath]) > 0 {
+			color.Yellow("⚠️  Imagem anterior %s mantida: ainda usada por %d disco(s) ('images prune' remove-a quando deixar de ser usada)",
+				filepath.Base(currentPath), len(users[currentPath]))
+		} else if err := kvm.removeBaseImage(currentPath); err != nil {
+			color.Yellow("⚠️  Erro ao remover imagem anterior %s: %v", currentPath, err)
+		}
+	}
+	color.Green("✅ Imagem da distro %s atualizada: %s", distro, filepath.Base(target))
+	return nil
+}
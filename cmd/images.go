@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// qemuImageInfo representa os campos usados da saída de "qemu-img info --output=json"
+type qemuImageInfo struct {
+	Format              string `json:"format"`
+	VirtualSize         int64  `json:"virtual-size"`
+	BackingFilename     string `json:"backing-filename"`
+	FullBackingFilename string `json:"full-backing-filename"`
+}
+
+// readImageInfo lê as informações de uma imagem com qemu-img
+func readImageInfo(path string) (*qemuImageInfo, error) {
+	output, err := execCommandOutput("qemu-img", "info", "--force-share", "--output=json", path)
+	if err != nil {
+		return nil, err
+	}
+	info := &qemuImageInfo{}
+	if err := json.Unmarshal([]byte(output), info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// imageFormat retorna o formato (qcow2, raw, ...) de uma imagem
+func imageFormat(path string) (string, error) {
+	info, err := readImageInfo(path)
+	if err != nil {
+		return "", err
+	}
+	return info.Format, nil
+}
+
+// baseImageUsers retorna, para cada imagem base, os discos de VM que a usam como backing file.
+// Uma imagem base presente neste mapa nunca pode ser removida.
+func (kvm *KVMCompose) baseImageUsers() map[string][]string {
+	users := make(map[string][]string)
+
+	// No storage pool, o volume base tem o mesmo nome do ficheiro em cache
+	if kvm.usePool() {
+		upstreamDir := expandPath(kvm.appConfig.Images.PathUpstreamImages)
+		for backing, disks := range kvm.poolBaseImageUsers() {
+			base := filepath.Join(upstreamDir, filepath.Base(backing))
+			users[base] = append(users[base], disks...)
+		}
+		return users
+	}
+
+	vmImagesDir := expandPath(kvm.appConfig.Images.PathVMImages)
+	entries, err := os.ReadDir(vmImagesDir)
+	if err != nil {
+		return users
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		diskPath := filepath.Join(vmImagesDir, entry.Name())
+		info, err := readImageInfo(diskPath)
+		if err != nil {
+			continue
+		}
+		backing := info.FullBackingFilename
+		if backing == "" {
+			backing = info.BackingFilename
+		}
+		if backing == "" {
+			continue
+		}
+		if !filepath.IsAbs(backing) {
+			backing = filepath.Join(vmImagesDir, backing)
+		}
+		backing = filepath.Clean(backing)
+		users[backing] = append(users[backing], diskPath)
+	}
+	return users
+}
+
+// cachedImage representa uma imagem base no cache de imagens upstream
+type cachedImage struct {
+	Distro  string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Exists  bool
+}
+
+// cachedImages retorna as imagens de todas as distros conhecidas e os restantes ficheiros do cache
+func (kvm *KVMCompose) cachedImages() []cachedImage {
+	var images []cachedImage
+	seen := make(map[string]bool)
+	for _, distro := range listDistros() {
+		image := cachedImage{Distro: distro, Path: kvm.getDistroImagePath(distro)}
+		if info, err := os.Stat(image.Path); err == nil {
+			image.Exists, image.Size, image.ModTime = true, info.Size(), info.ModTime()
+			if distroInfo, err := loadDistroInfo(distro); err == nil {
+				if meta := loadImageMeta(filepath.Dir(image.Path), distroInfo); !meta.DownloadedAt.IsZero() {
+					image.ModTime = meta.DownloadedAt
+				}
+			}
+		}
+		seen[image.Path] = true
+		images = append(images, image)
+	}
+
+	upstreamDir := expandPath(kvm.appConfig.Images.PathUpstreamImages)
+	entries, _ := os.ReadDir(upstreamDir)
+	for _, entry := range entries {
+		path := filepath.Join(upstreamDir, entry.Name())
+		// Metadados e downloads parciais (.part) não são imagens
+		if entry.IsDir() || seen[path] || strings.HasSuffix(entry.Name(), imageMetaSuffix) || strings.HasSuffix(entry.Name(), partialSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		images = append(images, cachedImage{Path: path, Size: info.Size(), ModTime: info.ModTime(), Exists: true})
+	}
+	return images
+}
+
+// composeImageUsers retorna, para cada imagem base, as VMs do compose que a usam
+func (kvm *KVMCompose) composeImageUsers() map[string][]string {
+	users := make(map[string][]string)
+	for _, vm := range kvm.config.VMs {
+		path := kvm.getBaseImagePath(&vm)
+		users[path] = append(users[path], vm.Name)
+	}
+	return users
+}
+
+// removeBaseImage remove uma imagem base do cache (e do storage pool, se usado)
+func (kvm *KVMCompose) removeBaseImage(path string) error {
+	if err := kvm.removeBaseVolume(filepath.Base(path)); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// formatSize formata um tamanho em bytes para leitura humana
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
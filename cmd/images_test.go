@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCachedImagesSkipsDownloadFiles(t *testing.T) {
+	dir := t.TempDir()
+	kvm := &KVMCompose{appConfig: &AppConfig{}}
+	kvm.appConfig.Images.PathUpstreamImages = dir
+	for _, name := range []string{"custom.qcow2", "custom.qcow2" + imageMetaSuffix, "novo.qcow2" + partialSuffix, "novo.qcow2" + partialSuffix + imageMetaSuffix} {
+		os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644)
+	}
+
+	var found []string
+	for _, image := range kvm.cachedImages() {
+		if image.Exists && filepath.Dir(image.Path) == dir {
+			found = append(found, filepath.Base(image.Path))
+		}
+	}
+	if len(found) != 1 || found[0] != "custom.qcow2" {
+		t.Errorf("imagens %v, esperado só custom.qcow2", found)
+	}
+}
+
+func TestImagesPruneAbortsOnBrokenCompose(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "custom.qcow2")
+	os.WriteFile(image, []byte("x"), 0644)
+	composeFile := filepath.Join(dir, "kvm-compose.yaml")
+	os.WriteFile(composeFile, []byte("vms: [\n"), 0644)
+
+	kvm := &KVMCompose{composeFile: composeFile, appConfig: &AppConfig{}}
+	kvm.appConfig.Images.PathUpstreamImages = dir
+	if err := kvm.ImagesPrune(false); err == nil || !strings.Contains(err.Error(), "YAML") {
+		t.Errorf("esperado erro do compose, obtido %v", err)
+	}
+	if _, err := os.Stat(image); err != nil {
+		t.Error("imagem removida com o compose inválido")
+	}
+
+	// Sem compose, não há imagens do projeto a proteger
+	kvm = &KVMCompose{composeFile: filepath.Join(dir, "nao-existe.yaml"), appConfig: &AppConfig{}}
+	if err := kvm.loadOptionalConfig(); err != nil {
+		t.Errorf("compose inexistente: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// ImagesList lista as imagens base em cache e as VMs que as usam
+func (kvm *KVMCompose) ImagesList() error {
+	// O compose é opcional: sem ele apenas não se mostram as VMs do projeto
+	if err := kvm.loadOptionalConfig(); err != nil {
+		return err
+	}
+	composeUsers := kvm.composeImageUsers()
+	diskUsers := kvm.baseImageUsers()
+
+	color.Cyan("=== Imagens base em %s ===", expandPath(kvm.appConfig.Images.PathUpstreamImages))
+	color.New(color.FgGreen, color.Bold).Printf("%-14s %-50s %-10s %-17s %s\n", "Distro", "Ficheiro", "Tamanho", "Baixada em", "VMs")
+	color.New(color.FgGreen, color.Bold).Printf("%-14s %-50s %-10s %-17s %s\n",
+		strings.Repeat("-", 14), strings.Repeat("-", 50), strings.Repeat("-", 10), strings.Repeat("-", 17), strings.Repeat("-", 20))
+
+	for _, image := range kvm.cachedImages() {
+		distro := image.Distro
+		if distro == "" {
+			distro = "-"
+		}
+		size, date := "-", "não baixada"
+		if image.Exists {
+			size = formatSize(image.Size)
+			date = image.ModTime.Format("2006-01-02 15:04")
+		}
+		users := strings.Join(composeUsers[image.Path], ", ")
+		if n := len(diskUsers[image.Path]); n > 0 {
+			users = strings.TrimSpace(fmt.Sprintf("%s [%d discos]", users, n))
+		}
+		if users == "" {
+			users = "-"
+		}
+		fmt.Printf("%-14s %-50s %-10s %-17s %s\n", distro, filepath.Base(image.Path), size, date, users)
+	}
+	return nil
+}
+
+// ImagesPull baixa as imagens das distros indicadas ou, sem argumentos, as imagens base das VMs do compose.
+// Com refresh, as imagens em cache são atualizadas se a versão remota mudou.
+func (kvm *KVMCompose) ImagesPull(distros []string, refresh bool) error {
+	color.Cyan("=== Baixando imagens base ===")
+	failedCount := 0
+	pull := func(name string, distroInfo *DistroInfo) {
+		if err := kvm.downloadImage(name, distroInfo, refresh); err != nil {
+			color.Red("❌ %s: %v", name, err)
+			failedCount++
+			return
+		}
+		if kvm.usePool() {
+			if err := kvm.ensureBaseVolume(kvm.imagePath(distroInfo)); err != nil {
+				color.Red("❌ %s: %v", name, err)
+				failedCount++
+			}
+		}
+	}
+
+	if len(distros) > 0 {
+		for _, distro := range distros {
+			distroInfo, err := loadDistroInfo(distro)
+			if err != nil {
+				color.Red("❌ %s: %v", distro, err)
+				failedCount++
+				continue
+			}
+			pull(distro, distroInfo)
+		}
+	} else {
+		if err := kvm.loadConfig(); err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, vm := range kvm.config.VMs {
+			distroInfo, err := vmDistroInfo(&vm)
+			if err != nil {
+				color.Red("❌ %s: %v", vm.Name, err)
+				failedCount++
+				continue
+			}
+			if !seen[distroInfo.URL] {
+				seen[distroInfo.URL] = true
+				pull(vmImageName(&vm), distroInfo)
+			}
+		}
+	}
+
+	if failedCount > 0 {
+		return fmt.Errorf("%d imagem(ns) com falha", failedCount)
+	}
+	return nil
+}
+
+// ImagesRemove remove imagens base do cache, recusando as que ainda suportam discos de VMs
+func (kvm *KVMCompose) ImagesRemove(targets []string) error {
+	upstreamDir := expandPath(kvm.appConfig.Images.PathUpstreamImages)
+	diskUsers := kvm.baseImageUsers()
+
+	failedCount := 0
+	for _, target := range targets {
+		path := filepath.Join(upstreamDir, filepath.Base(target))
+		if _, err := loadDistroInfo(target); err == nil {
+			path = kvm.getDistroImagePath(target)
+		}
+		if users := diskUsers[path]; len(users) > 0 {
+			color.Red("❌ %s está em uso como imagem base de: %s", filepath.Base(path), strings.Join(users, ", "))
+			failedCount++
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			color.Yellow("⚠️  Imagem %s não encontrada", path)
+			continue
+		}
+		if err := kvm.removeBaseImage(path); err != nil {
+			color.Red("❌ Erro ao remover %s: %v", path, err)
+			failedCount++
+			continue
+		}
+		color.Green("🗑️  Imagem %s removida", path)
+	}
+	if failedCount > 0 {
+		return fmt.Errorf("%d imagem(ns) não removida(s)", failedCount)
+	}
+	return nil
+}
+
+// ImagesPrune remove as imagens base que nenhuma VM usa. Sem all, as imagens
+// das distros do compose atual também são mantidas.
+func (kvm *KVMCompose) ImagesPrune(all bool) error {
+	// Um compose com erros abortaria o prune: as imagens dele pareceriam sem uso
+	if !all {
+		if err := kvm.loadOptionalConfig(); err != nil {
+			return err
+		}
+	}
+	composeUsers := kvm.composeImageUsers()
+	diskUsers := kvm.baseImageUsers()
+
+	color.Cyan("=== Removendo imagens base sem uso ===")
+	var freed int64
+	removedCount := 0
+	for _, image := range kvm.cachedImages() {
+		if !image.Exists || len(diskUsers[image.Path]) > 0 {
+			continue
+		}
+		if !all && len(composeUsers[image.Path]) > 0 {
+			continue
+		}
+		if err := kvm.removeBaseImage(image.Path); err != nil {
+			color.Red("❌ Erro ao remover %s: %v", image.Path, err)
+			continue
+		}
+		color.Green("🗑️  %s removida (%s)", filepath.Base(image.Path), formatSize(image.Size))
+		freed += image.Size
+		removedCount++
+	}
+
+	fmt.Println()
+	color.Cyan("=== Resumo ===")
+	fmt.Printf("Imagens removidas: %d\n", removedCount)
+	fmt.Printf("Espaço libertado: %s\n", formatSize(freed))
+	return nil
+}
+
+var (
+	imagesPruneAll    bool
+	imagesPullRefresh bool
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Gerir as imagens base das distros",
+}
+
+var imagesListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "Listar as imagens base em cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.ImagesList(); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagesPullCmd = &cobra.Command{
+	Use:   "pull [distro...]",
+	Short: "Baixar as imagens base das distros do compose (ou das indicadas)",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.ImagesPull(args, imagesPullRefresh); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagesRemoveCmd = &cobra.Command{
+	Use:     "rm <distro|ficheiro>...",
+	Aliases: []string{"remove"},
+	Short:   "Remover imagens base do cache",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.ImagesRemove(args); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imagesPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remover as imagens base que nenhuma VM usa",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.ImagesPrune(imagesPruneAll); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Register images command
+	imagesPullCmd.Flags().BoolVar(&imagesPullRefresh, "refresh", false, "Baixar novamente as imagens cuja versão remota mudou (ETag/Last-Modified)")
+	imagesPruneCmd.Flags().BoolVarP(&imagesPruneAll, "all", "a", false, "Remover também as imagens usadas apenas pelo compose atual (VMs ainda não criadas)")
+	imagesCmd.AddCommand(imagesListCmd)
+	imagesCmd.AddCommand(imagesPullCmd)
+	imagesCmd.AddCommand(imagesRemoveCmd)
+	imagesCmd.AddCommand(imagesPruneCmd)
+	rootCmd.AddCommand(imagesCmd)
+}
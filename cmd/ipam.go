@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// defaultNetworkName é o nome do pool definido em [network] no config.ini,
+// usado pelas interfaces que não referenciam uma rede do compose
+const defaultNetworkName = "default"
+
+// ipamState representa as alocações persistidas: rede -> "vm/índice da interface" -> IP
+type ipamState struct {
+	Networks map[string]map[string]string `json:"networks"`
+}
+
+// ipPool representa a faixa de endereços de uma rede de onde o IPAM aloca IPs
+type ipPool struct {
+	subnet  *net.IPNet
+	prefix  int
+	first   uint32
+	last    uint32
+	gateway string
+}
+
+// ipamStatePath retorna o caminho do ficheiro de alocações do projeto
+func (kvm *KVMCompose) ipamStatePath() string {
+	return filepath.Join(kvm.projectDir(), "ipam.json")
+}
+
+// loadIPAMState lê as alocações persistidas do projeto
+func (kvm *KVMCompose) loadIPAMState() (*ipamState, error) {
+	state := &ipamState{Networks: map[string]map[string]string{}}
+	data, err := os.ReadFile(kvm.ipamStatePath())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler alocações de IP: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("erro ao fazer parse de %s: %v", kvm.ipamStatePath(), err)
+	}
+	if state.Networks == nil {
+		state.Networks = map[string]map[string]string{}
+	}
+	return state, nil
+}
+
+// saveIPAMState grava as alocações do projeto
+func (kvm *KVMCompose) saveIPAMState(state *ipamState) error {
+	if _, err := kvm.ensureProjectDir(); err != nil {
+		return fmt.Errorf("erro ao criar diretório do projeto: %v", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(kvm.ipamStatePath(), append(data, '\n'), 0644)
+}
+
+// networkPool retorna o pool de uma rede, ou nil se a rede não tiver subnet
+func (kvm *KVMCompose) networkPool(name string) (*ipPool, error) {
+	if name == defaultNetworkName {
+		if _, declared := kvm.config.Networks[name]; !declared {
+			network := kvm.appConfig.Network
+			if network.Subnet == "" {
+				return nil, nil
+			}
+			return newIPPool(network.Subnet, network.IPRange, network.Gateway)
+		}
+	}
+	def := kvm.config.Networks[name]
+	if def.Subnet == "" {
+		return nil, nil
+	}
+	gateway := def.Gateway4
+	if gateway == "" {
+		gateway = kvm.appConfig.Network.Gateway
+	}
+	pool, err := newIPPool(def.Subnet, def.IPRange, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("rede '%s': %v", name, err)
+	}
+	return pool, nil
+}
+
+// newIPPool cria um pool a partir de uma subnet CIDR e de uma faixa opcional
+// no formato "inicio-fim" ou CIDR
+func newIPPool(subnet, ipRange, gateway string) (*ipPool, error) {
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(subnet))
+	if err != nil || ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("subnet inválida '%s'", subnet)
+	}
+	prefix, bits := ipNet.Mask.Size()
+	network := ipToUint32(ipNet.IP)
+	broadcast := network | (1<<uint(bits-prefix) - 1)
+
+	pool := &ipPool{subnet: ipNet, prefix: prefix, first: network + 1, last: broadcast - 1, gateway: gateway}
+	if prefix >= 31 {
+		pool.first, pool.last = network, broadcast
+	}
+
+	ipRange = strings.TrimSpace(ipRange)
+	switch {
+	case ipRange == "":
+	case strings.Contains(ipRange, "/"):
+		_, rangeNet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			return nil, fmt.Errorf("ip_range inválido '%s'", ipRange)
+		}
+		rangePrefix, _ := rangeNet.Mask.Size()
+		start := ipToUint32(rangeNet.IP)
+		end := start | (1<<uint(bits-rangePrefix) - 1)
+		pool.first, pool.last = max(pool.first, start), min(pool.last, end)
+	default:
+		bounds := strings.SplitN(ipRange, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("ip_range inválido '%s' (use inicio-fim ou CIDR)", ipRange)
+		}
+		start := net.ParseIP(strings.TrimSpace(bounds[0])).To4()
+		end := net.ParseIP(strings.TrimSpace(bounds[1])).To4()
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("ip_range inválido '%s'", ipRange)
+		}
+		pool.first, pool.last = max(pool.first, ipToUint32(start)), min(pool.last, ipToUint32(end))
+	}
+	if pool.first > pool.last || !ipNet.Contains(uint32ToIP(pool.first)) {
+		return nil, fmt.Errorf("ip_range '%s' fora da subnet %s", ipRange, subnet)
+	}
+	return pool, nil
+}
+
+// contains verifica se o IP pertence à faixa alocável do pool
+func (pool *ipPool) contains(ip string) bool {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return false
+	}
+	v := ipToUint32(parsed)
+	return v >= pool.first && v <= pool.last
+}
+
+// next retorna o primeiro IP livre do pool
+func (pool *ipPool) next(used map[string]bool) (string, error) {
+	for v := pool.first; v <= pool.last && v >= pool.first; v++ {
+		ip := uint32ToIP(v).String()
+		if !used[ip] && ip != pool.gateway {
+			return ip, nil
+		}
+	}
+	return "", fmt.Errorf("não há IPs livres na subnet %s", pool.subnet)
+}
+
+// resolveNetworks aplica as redes nomeadas às interfaces das VMs e preenche os
+// IPs das interfaces sem guest_ipv4. Com allocate=false apenas as alocações já
+// persistidas são usadas; com allocate=true são alocados IPs novos, e com save
+// o estado é gravado (sem save as alocações novas ficam apenas em memória).
+func (kvm *KVMCompose) resolveNetworks(allocate, save bool) error {
+	state, err := kvm.loadIPAMState()
+	if err != nil {
+		return err
+	}
+
+	// IPs fixos declarados no compose nunca são alocados. Os IPs preenchidos pelo IPAM
+	// numa resolução anterior voltam a ser resolvidos, para que a alocação se mantenha no estado.
+	used := make(map[string]bool)
+	for i := range kvm.config.VMs {
+		for n := range kvm.config.VMs[i].Networks {
+			nic := &kvm.config.VMs[i].Networks[n]
+			if nic.leased {
+				nic.GuestIPv4, nic.leased = "", false
+			}
+			if nic.GuestIPv4 != "" {
+				used[nic.GuestIPv4] = true
+			}
+		}
+	}
+
+	pools := make(map[string]*ipPool)
+	getPool := func(name string) (*ipPool, error) {
+		if pool, ok := pools[name]; ok {
+			return pool, nil
+		}
+		pool, err := kvm.networkPool(name)
+		pools[name] = pool
+		return pool, err
+	}
+
+	type pendingNIC struct {
+		nic  *Network
+		pool string
+		key  string
+	}
+	var pending []pendingNIC
+	newState := &ipamState{Networks: map[string]map[string]string{}}
+
+	// 1ª passagem: redes nomeadas e reaproveitamento de alocações existentes
+	for i := range kvm.config.VMs {
+		vm := &kvm.config.VMs[i]
+		for n := range vm.Networks {
+			nic := &vm.Networks[n]
+			poolName := defaultNetworkName
+			if nic.Network != "" {
+				def, ok := kvm.config.Networks[nic.Network]
+				if !ok {
+					return fmt.Errorf("VM '%s': rede '%s' não declarada em networks", vm.Name, nic.Network)
+				}
+				poolName = nic.Network
+				if nic.HostBridge == "" {
+					nic.HostBridge = def.HostBridge
+				}
+				if nic.GuestGateway4 == "" {
+					nic.GuestGateway4 = def.Gateway4
+				}
+				if len(nic.GuestNameservers) == 0 {
+					nic.GuestNameservers = def.Nameservers
+				}
+			}
+
+			pool, err := getPool(poolName)
+			if err != nil {
+				return err
+			}
+			if pool == nil {
+				continue
+			}
+			if nic.GuestIPv4 != "" {
+				if nic.GuestPrefix == 0 && pool.subnet.Contains(net.ParseIP(nic.GuestIPv4)) {
+					nic.GuestPrefix = pool.prefix
+				}
+				continue
+			}
+			if nic.GuestPrefix == 0 {
+				nic.GuestPrefix = pool.prefix
+			}
+
+			key := vm.Name + "/" + strconv.Itoa(n)
+			if ip, ok := state.Networks[poolName][key]; ok && pool.contains(ip) && !used[ip] {
+				nic.GuestIPv4, nic.leased = ip, true
+				used[ip] = true
+				setLease(newState, poolName, key, ip)
+				continue
+			}
+			pending = append(pending, pendingNIC{nic: nic, pool: poolName, key: key})
+		}
+	}
+
+	if !allocate {
+		return nil
+	}
+
+	// 2ª passagem: alocar IPs novos para as interfaces restantes
+	for _, p := range pending {
+		ip, err := pools[p.pool].next(used)
+		if err != nil {
+			return fmt.Errorf("rede '%s': %v", p.pool, err)
+		}
+		p.nic.GuestIPv4, p.nic.leased = ip, true
+		used[ip] = true
+		setLease(newState, p.pool, p.key, ip)
+	}
+
+	if !save || reflect.DeepEqual(state.Networks, newState.Networks) {
+		return nil
+	}
+	return kvm.saveIPAMState(newState)
+}
+
+// setLease registra uma alocação no estado do IPAM
+func setLease(state *ipamState, network, key, ip string) {
+	if state.Networks[network] == nil {
+		state.Networks[network] = make(map[string]string)
+	}
+	state.Networks[network][key] = ip
+}
+
+// ipToUint32 converte um IPv4 para inteiro
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+// uint32ToIP converte um inteiro para IPv4
+func uint32ToIP(v uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, v)
+	return ip
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// ipamProject cria um projeto num diretório temporário com uma rede "lab"
+func ipamProject(t *testing.T, dir string, vms ...string) *KVMCompose {
+	t.Helper()
+	kvm := &KVMCompose{composeFile: filepath.Join(dir, "kvm-compose.yaml"), appConfig: &AppConfig{}}
+	kvm.config.Networks = map[string]ComposeNetwork{
+		"lab": {HostBridge: "br-lab", Subnet: "10.10.0.0/24", IPRange: "10.10.0.10-10.10.0.12", Gateway4: "10.10.0.1"},
+	}
+	for _, name := range vms {
+		kvm.config.VMs = append(kvm.config.VMs, VM{Name: name, Networks: []Network{{Network: "lab"}}})
+	}
+	return kvm
+}
+
+// ipamIPs retorna o IP da primeira interface de cada VM
+func ipamIPs(kvm *KVMCompose) map[string]string {
+	ips := make(map[string]string)
+	for _, vm := range kvm.config.VMs {
+		ips[vm.Name] = vm.Networks[0].GuestIPv4
+	}
+	return ips
+}
+
+func TestNewIPPool(t *testing.T) {
+	tests := []struct {
+		subnet, ipRange string
+		first, last     string
+	}{
+		{"192.168.1.0/24", "", "192.168.1.1", "192.168.1.254"},
+		{"192.168.1.0/24", "192.168.1.100-192.168.1.150", "192.168.1.100", "192.168.1.150"},
+		{"192.168.1.0/24", "192.168.1.128/25", "192.168.1.128", "192.168.1.254"},
+		{"10.0.0.0/31", "", "10.0.0.0", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		pool, err := newIPPool(tt.subnet, tt.ipRange, "")
+		if err != nil {
+			t.Fatalf("newIPPool(%q, %q): %v", tt.subnet, tt.ipRange, err)
+		}
+		if first, last := uint32ToIP(pool.first).String(), uint32ToIP(pool.last).String(); first != tt.first || last != tt.last {
+			t.Errorf("newIPPool(%q, %q) = %s-%s, esperado %s-%s", tt.subnet, tt.ipRange, first, last, tt.first, tt.last)
+		}
+	}
+
+	for _, bad := range [][2]string{{"nao-e-cidr", ""}, {"fd00::/64", ""}, {"10.0.0.0/24", "10.0.1.1-10.0.1.9"}, {"10.0.0.0/24", "10.0.0.9"}} {
+		if _, err := newIPPool(bad[0], bad[1], ""); err == nil {
+			t.Errorf("newIPPool(%q, %q) sem erro", bad[0], bad[1])
+		}
+	}
+}
+
+func TestIPPoolNextSkipsUsedAndGateway(t *testing.T) {
+	pool, err := newIPPool("10.0.0.0/29", "", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := pool.next(map[string]bool{"10.0.0.2": true})
+	if err != nil || ip != "10.0.0.3" {
+		t.Errorf("next = %s, %v; esperado 10.0.0.3", ip, err)
+	}
+
+	used := map[string]bool{}
+	for _, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"} {
+		used[ip] = true
+	}
+	if ip, err := pool.next(used); err == nil {
+		t.Errorf("subnet esgotada devolveu %s", ip)
+	}
+}
+
+func TestResolveNetworksAllocatesAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	kvm := ipamProject(t, dir, "web", "db")
+	if err := kvm.resolveNetworks(true, true); err != nil {
+		t.Fatal(err)
+	}
+	ips := ipamIPs(kvm)
+	if ips["web"] != "10.10.0.10" || ips["db"] != "10.10.0.11" {
+		t.Fatalf("IPs alocados %v", ips)
+	}
+	nic := kvm.config.VMs[0].Networks[0]
+	if nic.GuestPrefix != 24 || nic.HostBridge != "br-lab" || nic.GuestGateway4 != "10.10.0.1" {
+		t.Errorf("interface sem os valores da rede: %+v", nic)
+	}
+
+	// Novo carregamento, com uma VM acrescentada antes das existentes: os IPs mantêm-se
+	kvm = ipamProject(t, dir, "cache", "web", "db")
+	if err := kvm.resolveNetworks(true, true); err != nil {
+		t.Fatal(err)
+	}
+	ips = ipamIPs(kvm)
+	if ips["web"] != "10.10.0.10" || ips["db"] != "10.10.0.11" || ips["cache"] != "10.10.0.12" {
+		t.Errorf("IPs após recarregar %v", ips)
+	}
+
+	// Sem allocate, só as alocações gravadas são usadas
+	kvm = ipamProject(t, dir, "web", "novo")
+	if err := kvm.resolveNetworks(false, false); err != nil {
+		t.Fatal(err)
+	}
+	if ips = ipamIPs(kvm); ips["web"] != "10.10.0.10" || ips["novo"] != "" {
+		t.Errorf("IPs sem allocate %v", ips)
+	}
+}
+
+func TestResolveNetworksReleasesRemovedVMs(t *testing.T) {
+	dir := t.TempDir()
+	kvm := ipamProject(t, dir, "a", "b", "c")
+	if err := kvm.resolveNetworks(true, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// "b" saiu do compose: o IP dela fica livre para a VM nova
+	kvm = ipamProject(t, dir, "a", "c", "d")
+	if err := kvm.resolveNetworks(true, true); err != nil {
+		t.Fatal(err)
+	}
+	if ips := ipamIPs(kvm); ips["a"] != "10.10.0.10" || ips["c"] != "10.10.0.12" || ips["d"] != "10.10.0.11" {
+		t.Errorf("IPs após libertar %v", ips)
+	}
+	state, err := kvm.loadIPAMState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Networks["lab"]["b/0"]; ok {
+		t.Error("alocação da VM removida continua gravada")
+	}
+}
+
+func TestResolveNetworksExhaustedSubnet(t *testing.T) {
+	kvm := ipamProject(t, t.TempDir(), "a", "b", "c", "d")
+	if err := kvm.resolveNetworks(true, true); err == nil {
+		t.Error("esperado erro com mais VMs do que IPs na faixa")
+	}
+}
+
+func TestResolveNetworksKeepsFixedIPs(t *testing.T) {
+	kvm := ipamProject(t, t.TempDir(), "a", "b")
+	kvm.config.VMs[1].Networks[0].GuestIPv4 = "10.10.0.10"
+	if err := kvm.resolveNetworks(true, false); err != nil {
+		t.Fatal(err)
+	}
+	if ips := ipamIPs(kvm); ips["a"] != "10.10.0.11" || ips["b"] != "10.10.0.10" {
+		t.Errorf("IP fixo reutilizado: %v", ips)
+	}
+	if kvm.config.VMs[1].Networks[0].GuestPrefix != 24 {
+		t.Error("IP fixo sem prefixo da rede")
+	}
+}
+
+func TestResolveNetworksKeepsStateAfterLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	compose := `networks:
+  lab:
+    host_bridge: br-lab
+    subnet: 10.10.0.0/24
+    ip_range: 10.10.0.10-10.10.0.12
+    gateway4: 10.10.0.1
+vms:
+  - name: web
+    networks:
+      - network: lab
+  - name: db
+    networks:
+      - network: lab
+`
+	composeFile := filepath.Join(dir, "kvm-compose.yaml")
+	if err := os.WriteFile(composeFile, []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dois up seguidos: loadConfig preenche os IPs gravados e o up volta a resolver
+	for i := 0; i < 2; i++ {
+		kvm := &KVMCompose{composeFile: composeFile, appConfig: &AppConfig{}}
+		if err := kvm.loadConfig(); err != nil {
+			t.Fatal(err)
+		}
+		if err := kvm.resolveNetworks(true, true); err != nil {
+			t.Fatal(err)
+		}
+		if ips := ipamIPs(kvm); ips["web"] != "10.10.0.10" || ips["db"] != "10.10.0.11" {
+			t.Fatalf("up %d: IPs %v", i+1, ips)
+		}
+		state, err := kvm.loadIPAMState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"web/0": "10.10.0.10", "db/0": "10.10.0.11"}
+		if !reflect.DeepEqual(state.Networks["lab"], want) {
+			t.Errorf("up %d: estado %v, esperado %v", i+1, state.Networks, want)
+		}
+	}
+}
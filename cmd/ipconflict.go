@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// checkIPConflicts verifica conflitos de IP das VMs do compose antes da criação.
+// Retorna, por VM, a lista de conflitos encontrados.
+func (kvm *KVMCompose) checkIPConflicts(arpProbe bool) map[string][]string {
+	conflicts := make(map[string][]string)
+
+	// 1. IPs duplicados dentro do próprio compose
+	owners := make(map[string][]string)
+	for _, vm := range kvm.config.VMs {
+		for _, nic := range vm.Networks {
+			if nic.GuestIPv4 != "" {
+				owners[nic.GuestIPv4] = append(owners[nic.GuestIPv4], vm.Name)
+			}
+		}
+	}
+	for ip, names := range owners {
+		if len(names) < 2 {
+			continue
+		}
+		for _, name := range names {
+			conflicts[name] = append(conflicts[name], fmt.Sprintf("IP %s duplicado no compose (%s)", ip, strings.Join(names, ", ")))
+		}
+	}
+
+	// Apenas as VMs que ainda vão ser criadas são verificadas contra o exterior
+	var pending []VM
+	for _, vm := range kvm.config.VMs {
+		if !vmExists(vm.Name) {
+			pending = append(pending, vm)
+		}
+	}
+	if len(pending) == 0 {
+		return conflicts
+	}
+
+	// 2. Endereços conhecidos de outros domínios libvirt
+	known := kvm.otherDomainAddresses()
+	for _, vm := range pending {
+		for _, nic := range vm.Networks {
+			if domain, ok := known[nic.GuestIPv4]; ok {
+				conflicts[vm.Name] = append(conflicts[vm.Name], fmt.Sprintf("IP %s em uso pelo domínio libvirt %s", nic.GuestIPv4, domain))
+			}
+		}
+	}
+
+	// 3. Sondagem ARP na bridge de destino
+	if arpProbe {
+		if _, err := exec.LookPath("arping"); err != nil {
+			color.Yellow("⚠️  arping não encontrado, sondagem ARP ignorada")
+			return conflicts
+		}
+		for _, vm := range pending {
+			for _, nic := range vm.Networks {
+				// Interfaces ainda sem IP alocado não têm o que sondar
+				if nic.GuestIPv4 == "" {
+					continue
+				}
+				bridge := nic.HostBridge
+				if bridge == "" {
+					bridge = "br0"
+				}
+				if arpInUse(bridge, nic.GuestIPv4) {
+					conflicts[vm.Name] = append(conflicts[vm.Name], fmt.Sprintf("IP %s respondeu a ARP na bridge %s", nic.GuestIPv4, bridge))
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// otherDomainAddresses retorna os IPs conhecidos dos domínios libvirt que não pertencem ao compose
+func (kvm *KVMCompose) otherDomainAddresses() map[string]string {
+	addresses := make(map[string]string)
+	output, err := execCommandOutput("virsh", "list", "--all", "--name")
+	if err != nil {
+		return addresses
+	}
+
+	ours := make(map[string]bool)
+	for _, vm := range kvm.config.VMs {
+		ours[vm.Name] = true
+	}
+
+	for _, domain := range strings.Split(output, "\n") {
+		domain = strings.TrimSpace(domain)
+		if domain == "" || ours[domain] {
+			continue
+		}
+		for _, source := range []string{"lease", "arp", "agent"} {
+			out, err := execCommandOutput("virsh", "domifaddr", domain, "--source", source)
+			if err != nil {
+				continue
+			}
+			for _, ip := range parseDomIfAddr(out) {
+				addresses[ip] = domain
+			}
+		}
+	}
+	return addresses
+}
+
+// parseDomIfAddr extrai os IPv4 da saída de "virsh domifaddr"
+func parseDomIfAddr(output string) []string {
+	var ips []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "ipv4" {
+			continue
+		}
+		ips = append(ips, strings.SplitN(fields[3], "/", 2)[0])
+	}
+	return ips
+}
+
+// arpInUse verifica com arping (detecção de duplicados) se o IP responde na bridge
+func arpInUse(bridge, ip string) bool {
+	return arpingInUse(exec.Command("arping", arpingArgs(bridge, ip)...).Run())
+}
+
+// arpingArgs retorna os argumentos do arping em modo de detecção de duplicados (-D)
+func arpingArgs(bridge, ip string) []string {
+	return []string{"-D", "-q", "-c", "2", "-w", "3", "-I", bridge, ip}
+}
+
+// arpingInUse interpreta o resultado do arping -D: o código 1 indica que algum host
+// respondeu pelo IP; sucesso ou outros erros (ex.: bridge inexistente) não são conflito
+func arpingInUse(err error) bool {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode() == 1
+	}
+	return false
+}
+
+// printIPConflicts mostra os conflitos de IP encontrados
+func printIPConflicts(conflicts map[string][]string) {
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, reason := range conflicts[name] {
+			color.Red("❌ %s: %s", name, reason)
+		}
+	}
+}
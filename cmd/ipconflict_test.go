@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestParseDomIfAddr(t *testing.T) {
+	output := ` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+ vnet0      52:54:00:12:34:56    ipv4         192.168.1.50/24
+ vnet0      52:54:00:12:34:56    ipv6         fe80::5054:ff:fe12:3456/64
+ vnet1      52:54:00:ab:cd:ef    ipv4         10.10.0.10/24
+ -          -                    ipv4         10.10.0.11/24
+`
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"vários", output, []string{"192.168.1.50", "10.10.0.10", "10.10.0.11"}},
+		{"vazio", "", nil},
+		{"só cabeçalho", " Name       MAC address          Protocol     Address\n-----\n", nil},
+		{"sem prefixo", " vnet0 52:54:00:12:34:56 ipv4 192.168.1.7\n", []string{"192.168.1.7"}},
+	}
+	for _, tt := range tests {
+		if got := parseDomIfAddr(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseDomIfAddr = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestArpingInUse(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh não disponível")
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"ninguém respondeu", exec.Command("sh", "-c", "exit 0").Run(), false},
+		{"IP em uso", exec.Command("sh", "-c", "exit 1").Run(), true},
+		{"erro do arping", exec.Command("sh", "-c", "exit 2").Run(), false},
+		{"arping ausente", exec.ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := arpingInUse(tt.err); got != tt.want {
+			t.Errorf("%s: arpingInUse(%v) = %v, esperado %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestArpingArgs(t *testing.T) {
+	want := []string{"-D", "-q", "-c", "2", "-w", "3", "-I", "br-lab", "10.10.0.10"}
+	if got := arpingArgs("br-lab", "10.10.0.10"); !reflect.DeepEqual(got, want) {
+		t.Errorf("arpingArgs = %v, esperado %v", got, want)
+	}
+}
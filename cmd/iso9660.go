@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Imagem ISO9660 mínima com extensões Joliet: um único diretório (a raiz) com ficheiros pequenos,
+// suficiente para o seed NoCloud do cloud-init. Os nomes ISO9660 são maiúsculos e sem "-";
+// os nomes originais (ex.: "user-data") ficam na árvore Joliet, usada pelo Linux ao montar.
+
+const (
+	isoSectorSize = 2048
+	// isoSystemArea são os 16 setores reservados no início da imagem
+	isoSystemArea = 16
+	// Setores fixos: PVD, SVD Joliet, terminador, tabelas de caminhos e diretórios raiz
+	isoPVDSector         = 16
+	isoJolietSector      = 17
+	isoTerminatorSector  = 18
+	isoPathTableLSector  = 19
+	isoPathTableMSector  = 20
+	isoJolietPathLSector = 21
+	isoJolietPathMSector = 22
+	isoRootSector        = 23
+	isoJolietRootSector  = 24
+	isoFirstFileSector   = 25
+)
+
+// isoFile é um ficheiro da raiz da imagem ISO
+type isoFile struct {
+	Name string
+	Data []byte
+
+	sector uint32
+}
+
+// writeISO escreve em path uma imagem ISO9660/Joliet com o rótulo e os ficheiros indicados.
+// A imagem é gravada com permissões 0600: o seed contém o hash da password e as chaves de host.
+func writeISO(path, volumeID string, files []isoFile) error {
+	data, err := buildISO(volumeID, files, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile não altera as permissões de um seed já existente
+	return os.Chmod(path, 0600)
+}
+
+// buildISO constrói a imagem ISO9660/Joliet em memória
+func buildISO(volumeID string, files []isoFile, now time.Time) ([]byte, error) {
+	files = append([]isoFile{}, files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	// Dados dos ficheiros a seguir aos diretórios, cada um a começar num setor
+	sector := uint32(isoFirstFileSector)
+	for i := range files {
+		files[i].sector = sector
+		sector += uint32((len(files[i].Data) + isoSectorSize - 1) / isoSectorSize)
+	}
+	totalSectors := sector
+
+	image := make([]byte, int(totalSectors)*isoSectorSize)
+	at := func(s uint32) []byte { return image[int(s)*isoSectorSize : int(s+1)*isoSectorSize] }
+
+	// Diretórios raiz: primário (nomes ISO9660) e Joliet (nomes UCS-2)
+	primaryRoot, err := isoDirectory(isoRootSector, files, now, isoPrimaryName)
+	if err != nil {
+		return nil, err
+	}
+	jolietRoot, err := isoDirectory(isoJolietRootSector, files, now, isoJolietName)
+	if err != nil {
+		return nil, err
+	}
+	copy(at(isoRootSector), primaryRoot)
+	copy(at(isoJolietRootSector), jolietRoot)
+
+	// Tabelas de caminhos (apenas a raiz)
+	isoPathTable(at(isoPathTableLSector), isoRootSector, binary.LittleEndian)
+	isoPathTable(at(isoPathTableMSector), isoRootSector, binary.BigEndian)
+	isoPathTable(at(isoJolietPathLSector), isoJolietRootSector, binary.LittleEndian)
+	isoPathTable(at(isoJolietPathMSector), isoJolietRootSector, binary.BigEndian)
+
+	// Descritores de volume
+	isoVolumeDescriptor(at(isoPVDSector), 1, volumeID, totalSectors, isoPathTableLSector, isoPathTableMSector, isoRootSector, now)
+	isoVolumeDescriptor(at(isoJolietSector), 2, volumeID, totalSectors, isoJolietPathLSector, isoJolietPathMSector, isoJolietRootSector, now)
+	terminator := at(isoTerminatorSector)
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+
+	for _, f := range files {
+		copy(image[int(f.sector)*isoSectorSize:], f.Data)
+	}
+	return image, nil
+}
+
+// isoPrimaryName converte um nome para ISO9660 (ex.: user-data → USER_DATA.;1)
+func isoPrimaryName(name string) []byte {
+	upper := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if !strings.Contains(upper, ".") {
+		upper += "."
+	}
+	return []byte(upper + ";1")
+}
+
+// isoJolietName converte um nome para UCS-2 big-endian (Joliet)
+func isoJolietName(name string) []byte {
+	units := utf16.Encode([]rune(name))
+	out := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.BigEndian.PutUint16(out[2*i:], u)
+	}
+	return out
+}
+
+// isoDirectory gera o setor do diretório raiz com as entradas ".", ".." e os ficheiros
+func isoDirectory(self uint32, files []isoFile, now time.Time, name func(string) []byte) ([]byte, error) {
+	dir := isoDirRecord([]byte{0}, self, isoSectorSize, true, now)
+	dir = append(dir, isoDirRecord([]byte{1}, self, isoSectorSize, true, now)...)
+	for _, f := range files {
+		dir = append(dir, isoDirRecord(name(f.Name), f.sector, uint32(len(f.Data)), false, now)...)
+	}
+	if len(dir) > isoSectorSize {
+		return nil, fmt.Errorf("demasiados ficheiros para a imagem ISO")
+	}
+	return dir, nil
+}
+
+// isoDirRecord gera um registo de diretório (ISO9660 9.1)
+func isoDirRecord(id []byte, extent, size uint32, isDir bool, now time.Time) []byte {
+	length := 33 + len(id)
+	if length%2 != 0 {
+		length++
+	}
+	rec := make([]byte, length)
+	rec[0] = byte(length)
+	isoBothUint32(rec[2:10], extent)
+	isoBothUint32(rec[10:18], size)
+	rec[18] = byte(now.Year() - 1900)
+	rec[19] = byte(now.Month())
+	rec[20] = byte(now.Day())
+	rec[21] = byte(now.Hour())
+	rec[22] = byte(now.Minute())
+	rec[23] = byte(now.Second())
+	if isDir {
+		rec[25] = 2
+	}
+	isoBothUint16(rec[28:32], 1)
+	rec[32] = byte(len(id))
+	copy(rec[33:], id)
+	return rec
+}
+
+// isoPathTable escreve uma tabela de caminhos com apenas o diretório raiz
+func isoPathTable(buf []byte, rootSector uint32, order binary.ByteOrder) {
+	buf[0] = 1
+	order.PutUint32(buf[2:6], rootSector)
+	order.PutUint16(buf[6:8], 1)
+}
+
+// isoVolumeDescriptor escreve o descritor primário (tipo 1) ou Joliet (tipo 2)
+func isoVolumeDescriptor(buf []byte, kind byte, volumeID string, totalSectors, pathL, pathM, root uint32, now time.Time) {
+	joliet := kind == 2
+	text := func(field []byte, value string) {
+		if joliet {
+			for i := 0; i+1 < len(field); i += 2 {
+				field[i], field[i+1] = 0, ' '
+			}
+			copy(field, isoJolietName(value))
+			return
+		}
+		for i := range field {
+			field[i] = ' '
+		}
+		copy(field, value)
+	}
+
+	buf[0] = kind
+	copy(buf[1:6], "CD001")
+	buf[6] = 1
+	text(buf[8:40], "LINUX")
+	text(buf[40:72], volumeID)
+	isoBothUint32(buf[80:88], totalSectors)
+	if joliet {
+		// Sequência de escape do UCS-2 nível 3
+		copy(buf[88:91], "%/E")
+	}
+	isoBothUint16(buf[120:124], 1)
+	isoBothUint16(buf[124:128], 1)
+	isoBothUint16(buf[128:132], isoSectorSize)
+	isoBothUint32(buf[132:140], 10)
+	binary.LittleEndian.PutUint32(buf[140:144], pathL)
+	binary.BigEndian.PutUint32(buf[148:152], pathM)
+	copy(buf[156:190], isoDirRecord([]byte{0}, root, isoSectorSize, true, now))
+	text(buf[190:318], "")
+	text(buf[318:446], "")
+	text(buf[446:574], "")
+	text(buf[574:702], "KVM-COMPOSE")
+	text(buf[702:739], "")
+	text(buf[739:776], "")
+	text(buf[776:813], "")
+	stamp := now.Format("20060102150405") + "00"
+	copy(buf[813:829], stamp)
+	copy(buf[830:846], stamp)
+	copy(buf[847:863], strings.Repeat("0", 16))
+	copy(buf[864:880], stamp)
+	buf[881] = 1
+}
+
+// isoBothUint32 escreve um inteiro de 32 bits em little-endian seguido de big-endian
+func isoBothUint32(buf []byte, v uint32) {
+	binary.LittleEndian.PutUint32(buf[0:4], v)
+	binary.BigEndian.PutUint32(buf[4:8], v)
+}
+
+// isoBothUint16 escreve um inteiro de 16 bits em little-endian seguido de big-endian
+func isoBothUint16(buf []byte, v uint16) {
+	binary.LittleEndian.PutUint16(buf[0:2], v)
+	binary.BigEndian.PutUint16(buf[2:4], v)
+}
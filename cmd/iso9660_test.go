@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// isoEntries lê o diretório raiz indicado pelo descritor de volume no setor dado
+func isoEntries(t *testing.T, image []byte, descriptor int, joliet bool) map[string][]byte {
+	t.Helper()
+	vd := image[descriptor*isoSectorSize:]
+	root := vd[156:]
+	extent := binary.LittleEndian.Uint32(root[2:6])
+	size := binary.LittleEndian.Uint32(root[10:14])
+	dir := image[int(extent)*isoSectorSize : int(extent)*isoSectorSize+int(size)]
+
+	entries := make(map[string][]byte)
+	for pos := 0; pos < len(dir) && dir[pos] != 0; pos += int(dir[pos]) {
+		rec := dir[pos:]
+		id := rec[33 : 33+int(rec[32])]
+		if len(id) == 1 && id[0] <= 1 {
+			continue
+		}
+		name := string(id)
+		if joliet {
+			units := make([]uint16, len(id)/2)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(id[2*i:])
+			}
+			name = string(utf16.Decode(units))
+		}
+		fileExtent := binary.LittleEndian.Uint32(rec[2:6])
+		fileSize := binary.LittleEndian.Uint32(rec[10:14])
+		if binary.BigEndian.Uint32(rec[6:10]) != fileExtent || binary.BigEndian.Uint32(rec[14:18]) != fileSize {
+			t.Errorf("%s: valores little/big-endian diferentes", name)
+		}
+		entries[name] = image[int(fileExtent)*isoSectorSize : int(fileExtent)*isoSectorSize+int(fileSize)]
+	}
+	return entries
+}
+
+func TestBuildISO(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 3*isoSectorSize+10)
+	files := []isoFile{
+		{Name: "user-data", Data: []byte("#cloud-config\n")},
+		{Name: "meta-data", Data: []byte("instance-id: web\n")},
+		{Name: "network-config", Data: big},
+	}
+	image, err := buildISO("cidata", files, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Área de sistema, descritores e ficheiros alinhados a setores
+	if len(image)%isoSectorSize != 0 {
+		t.Fatalf("tamanho %d não é múltiplo do setor", len(image))
+	}
+	if want := (isoFirstFileSector + 1 + 1 + 4) * isoSectorSize; len(image) != want {
+		t.Errorf("tamanho %d, esperado %d", len(image), want)
+	}
+	if !bytes.Equal(image[:isoSystemArea*isoSectorSize], make([]byte, isoSystemArea*isoSectorSize)) {
+		t.Error("área de sistema não está vazia")
+	}
+	for sector, kind := range map[int]byte{isoPVDSector: 1, isoJolietSector: 2, isoTerminatorSector: 255} {
+		vd := image[sector*isoSectorSize:]
+		if vd[0] != kind || string(vd[1:6]) != "CD001" {
+			t.Errorf("setor %d: descritor %d/%q, esperado %d/CD001", sector, vd[0], vd[1:6], kind)
+		}
+	}
+	pvd := image[isoPVDSector*isoSectorSize:]
+	if label := strings.TrimRight(string(pvd[40:72]), " "); label != "cidata" {
+		t.Errorf("rótulo %q, esperado cidata", label)
+	}
+	if total := binary.LittleEndian.Uint32(pvd[80:84]); int(total)*isoSectorSize != len(image) {
+		t.Errorf("total de setores %d não corresponde à imagem", total)
+	}
+	if escape := string(image[isoJolietSector*isoSectorSize+88:][:3]); escape != "%/E" {
+		t.Errorf("sequência de escape Joliet %q", escape)
+	}
+
+	// Nomes ISO9660 no diretório primário e nomes originais na árvore Joliet
+	primary := isoEntries(t, image, isoPVDSector, false)
+	joliet := isoEntries(t, image, isoJolietSector, true)
+	for _, f := range files {
+		if got := joliet[f.Name]; !bytes.Equal(got, f.Data) {
+			t.Errorf("Joliet %s: conteúdo diferente (%d bytes)", f.Name, len(got))
+		}
+		name := string(isoPrimaryName(f.Name))
+		if got := primary[name]; !bytes.Equal(got, f.Data) {
+			t.Errorf("ISO9660 %s: conteúdo diferente (%d bytes)", name, len(got))
+		}
+	}
+}
+
+func TestISOPrimaryName(t *testing.T) {
+	tests := map[string]string{
+		"user-data":      "USER_DATA.;1",
+		"network-config": "NETWORK_CONFIG.;1",
+		"vendor.txt":     "VENDOR.TXT;1",
+	}
+	for name, want := range tests {
+		if got := string(isoPrimaryName(name)); got != want {
+			t.Errorf("isoPrimaryName(%q) = %q, esperado %q", name, got, want)
+		}
+	}
+}
+
+func TestBuildISOTooManyFiles(t *testing.T) {
+	var files []isoFile
+	for i := 0; i < 100; i++ {
+		files = append(files, isoFile{Name: strings.Repeat("f", 20) + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+	}
+	if _, err := buildISO("cidata", files, time.Now()); err == nil {
+		t.Error("esperado erro com mais entradas do que cabem num setor")
+	}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// consoleLogName é o ficheiro, no diretório de trabalho da VM, com a saída da consola série
+const consoleLogName = "console.log"
+
+// logsPollInterval é o intervalo entre leituras dos logs com --follow
+const logsPollInterval = 500 * time.Millisecond
+
+// logPrefixColors são as cores dos prefixos, atribuídas às VMs por ordem
+var logPrefixColors = []color.Attribute{color.FgCyan, color.FgGreen, color.FgYellow, color.FgMagenta, color.FgBlue, color.FgRed}
+
+// LogsOptions representa as opções do comando logs
+type LogsOptions struct {
+	Follow bool
+	// Tail é o número de linhas finais a mostrar de cada VM (negativo = todas)
+	Tail int
+}
+
+// consoleLogPath retorna o caminho do log da consola série da VM (.kvm-compose/vms/<vm>/console.log)
+func (kvm *KVMCompose) consoleLogPath(vmName string) string {
+	return filepath.Join(kvm.vmWorkDirPath(vmName), consoleLogName)
+}
+
+// prepareConsoleLog cria o log da consola série da VM antes do virt-install, para que
+// o ficheiro pertença ao utilizador; o virtlogd do libvirt apenas lhe acrescenta dados.
+// Com SELinux ativo, o ficheiro recebe o rótulo virt_log_t que o virtlogd pode escrever.
+func (kvm *KVMCompose) prepareConsoleLog(vmName string) (string, error) {
+	if _, err := kvm.vmWorkDir(vmName); err != nil {
+		return "", err
+	}
+	path := kvm.consoleLogPath(vmName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	if execCommand("selinuxenabled") == nil {
+		if err := execCommand("chcon", "-t", "virt_log_t", path); err != nil {
+			color.Yellow("⚠️  Erro ao aplicar o rótulo SELinux virt_log_t a %s: %v", path, err)
+		}
+	}
+	return path, nil
+}
+
+// consoleLog é o log de uma VM a ser lido, com o prefixo e a posição já lida
+type consoleLog struct {
+	path    string
+	prefix  string
+	offset  int64
+	partial []byte
+}
+
+// Logs mostra a consola série das VMs indicadas (todas, por omissão) com um prefixo por VM
+func (kvm *KVMCompose) Logs(names []string, opts LogsOptions) error {
+	if err := kvm.loadConfig(); err != nil {
+		return err
+	}
+	vms, err := kvm.selectVMs(names)
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, vm := range vms {
+		if len(vm.Name) > width {
+			width = len(vm.Name)
+		}
+	}
+
+	var logs []*consoleLog
+	for i, vm := range vms {
+		prefix := color.New(logPrefixColors[i%len(logPrefixColors)]).Sprintf("%-*s |", width, vm.Name)
+		log := &consoleLog{path: kvm.consoleLogPath(vm.Name), prefix: prefix}
+		_, err := os.Stat(log.path)
+		if os.IsPermission(err) {
+			return fmt.Errorf("sem permissão para ler %s (execute com sudo)", log.path)
+		}
+		if err != nil && !opts.Follow {
+			color.Yellow("⚠️  VM %s sem log da consola (%s)", vm.Name, log.path)
+			continue
+		}
+		if err := log.tail(opts.Tail); err != nil {
+			return fmt.Errorf("erro ao ler %s: %v", log.path, err)
+		}
+		logs = append(logs, log)
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	// Acompanhar os logs até Ctrl+C
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+			for _, log := range logs {
+				if err := log.follow(); err != nil {
+					color.Yellow("⚠️  Erro ao ler %s: %v", log.path, err)
+				}
+			}
+		}
+	}
+}
+
+// tail mostra as últimas n linhas do log (todas se n for negativo) e guarda a posição final
+func (l *consoleLog) tail(n int) error {
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	l.offset = int64(len(data))
+
+	// Uma linha incompleta no fim fica à espera do resto
+	if i := bytes.LastIndexByte(data, '\n'); i < len(data)-1 {
+		l.partial = append([]byte{}, data[i+1:]...)
+		data = data[:i+1]
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		l.print(line)
+	}
+	return nil
+}
+
+// follow mostra as linhas acrescentadas desde a última leitura
+func (l *consoleLog) follow() error {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// O log foi recriado (ex.: down e up): recomeçar do início
+	if info.Size() < l.offset {
+		l.offset = 0
+		l.partial = nil
+	}
+	if _, err := file.Seek(l.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		chunk, err := reader.ReadBytes('\n')
+		l.offset += int64(len(chunk))
+		if err == io.EOF {
+			l.partial = append(l.partial, chunk...)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l.print(string(append(l.partial, chunk...)))
+		l.partial = nil
+	}
+}
+
+// print mostra uma linha do log com o prefixo da VM
+func (l *consoleLog) print(line string) {
+	fmt.Printf("%s %s\n", l.prefix, strings.TrimRight(line, "\r\n"))
+}
+
+var logsOptions LogsOptions
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [vm...]",
+	Short: "Mostrar a consola série das VMs (.kvm-compose/vms/<vm>/console.log)",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.Logs(args, logsOptions); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsOptions.Follow, "follow", "f", false, "Acompanhar os logs (Ctrl+C para sair)")
+	logsCmd.Flags().IntVar(&logsOptions.Tail, "tail", -1, "Número de linhas finais a mostrar de cada VM (-1 = todas)")
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// resolveMACs define o MAC de cada interface: o campo mac do compose ou um MAC
+// determinístico derivado do projeto, do nome da VM e do índice da interface.
+// MACs repetidos no projeto (declarados ou por colisão do hash) são um erro.
+func (kvm *KVMCompose) resolveMACs() error {
+	project := kvm.projectName()
+	owners := make(map[string]string)
+	for i := range kvm.config.VMs {
+		vm := &kvm.config.VMs[i]
+		for n := range vm.Networks {
+			nic := &vm.Networks[n]
+			if nic.MAC == "" {
+				nic.MAC = deterministicMAC(project, vm.Name, n)
+			} else {
+				mac, err := net.ParseMAC(nic.MAC)
+				if err != nil || len(mac) != 6 {
+					return fmt.Errorf("VM '%s': MAC inválido '%s'", vm.Name, nic.MAC)
+				}
+				nic.MAC = strings.ToLower(mac.String())
+			}
+
+			owner := fmt.Sprintf("%s (interface %d)", vm.Name, n)
+			if other, ok := owners[nic.MAC]; ok {
+				return fmt.Errorf("MAC %s repetido em %s e %s (defina mac numa delas)", nic.MAC, other, owner)
+			}
+			owners[nic.MAC] = owner
+		}
+	}
+	return nil
+}
+
+// deterministicMAC gera um MAC estável com o prefixo 52:54:00 do QEMU/KVM
+func deterministicMAC(project, vmName string, index int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", project, vmName, index)))
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", sum[0], sum[1], sum[2])
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/paulozagaloneves/kvm-compose/templates"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDeterministicMAC(t *testing.T) {
+	mac := deterministicMAC("lab", "web", 0)
+	if !regexp.MustCompile(`^52:54:00(:[0-9a-f]{2}){3}$`).MatchString(mac) {
+		t.Fatalf("MAC com formato inválido: %s", mac)
+	}
+	if again := deterministicMAC("lab", "web", 0); again != mac {
+		t.Errorf("MAC não é estável: %s e %s", mac, again)
+	}
+	for _, other := range []string{deterministicMAC("lab", "web", 1), deterministicMAC("lab", "db", 0), deterministicMAC("outro", "web", 0)} {
+		if other == mac {
+			t.Errorf("entradas diferentes deram o mesmo MAC %s", mac)
+		}
+	}
+}
+
+func TestResolveMACs(t *testing.T) {
+	kvm := &KVMCompose{composeFile: "/tmp/lab/kvm-compose.yaml", appConfig: &AppConfig{}}
+	kvm.config.VMs = []VM{
+		{Name: "web", Networks: []Network{{}, {MAC: "52:54:00:AA:BB:CC"}}},
+	}
+	if err := kvm.resolveMACs(); err != nil {
+		t.Fatal(err)
+	}
+	nics := kvm.config.VMs[0].Networks
+	if nics[0].MAC != deterministicMAC("lab", "web", 0) {
+		t.Errorf("MAC derivado %s", nics[0].MAC)
+	}
+	if nics[1].MAC != "52:54:00:aa:bb:cc" {
+		t.Errorf("MAC declarado não normalizado: %s", nics[1].MAC)
+	}
+
+	kvm.config.VMs = []VM{{Name: "web", Networks: []Network{{MAC: "nao-e-mac"}}}}
+	if err := kvm.resolveMACs(); err == nil {
+		t.Error("MAC inválido aceite")
+	}
+
+	// Um MAC declarado igual ao derivado de outra interface
+	kvm.config.VMs = []VM{
+		{Name: "web", Networks: []Network{{}}},
+		{Name: "db", Networks: []Network{{MAC: strings.ToUpper(deterministicMAC("lab", "web", 0))}}},
+	}
+	if err := kvm.resolveMACs(); err == nil || !strings.Contains(err.Error(), "repetido") {
+		t.Errorf("MAC repetido não detetado: %v", err)
+	}
+}
+
+func TestNetworkConfigRendersAllNICs(t *testing.T) {
+	data, err := templates.FS.ReadFile("network-config.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := template.Must(template.New("network-config").Funcs(templateFuncs()).Parse(string(data)))
+
+	kvm := &KVMCompose{composeFile: "/tmp/lab/kvm-compose.yaml", appConfig: &AppConfig{}}
+	vm := &VM{Name: "web", Networks: []Network{
+		{MAC: "52:54:00:00:00:01", GuestIPv4: "10.0.0.5", GuestGateway4: "10.0.0.1", GuestNameservers: []string{"1.1.1.1"}},
+		{MAC: "52:54:00:00:00:02", GuestIPv4: "10.1.0.5", GuestPrefix: 16, GuestGateway4: "10.1.0.1"},
+	}}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, kvm.templateContext(vm, &DistroInfo{})); err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Ethernets map[string]struct {
+			Match     map[string]string `yaml:"match"`
+			Addresses []string          `yaml:"addresses"`
+			Gateway4  string            `yaml:"gateway4"`
+		} `yaml:"ethernets"`
+	}
+	if err := yaml.Unmarshal(out.Bytes(), &config); err != nil {
+		t.Fatalf("network-config inválido: %v\n%s", err, out.String())
+	}
+	first, second := config.Ethernets["enp1s0"], config.Ethernets["enp2s0"]
+	if first.Match["macaddress"] != "52:54:00:00:00:01" || first.Addresses[0] != "10.0.0.5/24" || first.Gateway4 != "10.0.0.1" {
+		t.Errorf("primeira interface: %+v", first)
+	}
+	if second.Match["macaddress"] != "52:54:00:00:00:02" || len(second.Addresses) != 1 || second.Addresses[0] != "10.1.0.5/16" {
+		t.Errorf("segunda interface: %+v", second)
+	}
+	if second.Gateway4 != "" {
+		t.Errorf("segunda interface com rota por omissão %s", second.Gateway4)
+	}
+}
+
+func TestNICNameAt(t *testing.T) {
+	for _, tt := range []struct {
+		nicName string
+		index   int
+		want    string
+	}{{"", 0, "enp1s0"}, {"", 2, "enp3s0"}, {"eth0", 0, "eth0"}, {"eth0", 1, "eth1"}} {
+		if got := (&DistroInfo{NICName: tt.nicName}).nicNameAt(tt.index); got != tt.want {
+			t.Errorf("nicNameAt(%q, %d) = %s, esperado %s", tt.nicName, tt.index, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// phoneHomePath é o caminho HTTP para onde o cloud-init de cada VM reporta (/phone-home/<vm>)
+const phoneHomePath = "/phone-home/"
+
+// phoneHomeReportName é o ficheiro, no diretório de trabalho da VM, com o último report recebido
+const phoneHomeReportName = "phone-home.json"
+
+// phoneHomeReport é o que uma VM reportou ao terminar o cloud-init
+type phoneHomeReport struct {
+	VM         string            `json:"vm"`
+	InstanceID string            `json:"instance_id"`
+	Hostname   string            `json:"hostname"`
+	FQDN       string            `json:"fqdn,omitempty"`
+	HostKeys   map[string]string `json:"host_keys,omitempty"`
+	RemoteAddr string            `json:"remote_addr"`
+	ReadyAt    time.Time         `json:"ready_at"`
+	// Elapsed é o tempo desde a criação do seed até ao report
+	Elapsed time.Duration `json:"elapsed"`
+	// HostKeysMatch indica se as chaves reportadas são as fixadas pelo kvm-compose
+	HostKeysMatch bool `json:"host_keys_match"`
+}
+
+// phoneHomeServer recebe os reports phone_home das VMs criadas no up.
+// Há um listener por endereço do host nas bridges usadas pelas VMs.
+type phoneHomeServer struct {
+	kvm  *KVMCompose
+	port int
+
+	mu        sync.Mutex
+	listeners map[string]string // IP do host -> URL base
+	servers   []*http.Server
+	started   map[string]time.Time
+	reports   map[string]*phoneHomeReport
+	arrived   chan string
+}
+
+// newPhoneHomeServer cria o servidor; os listeners são abertos à medida que as VMs precisam deles
+func newPhoneHomeServer(kvm *KVMCompose, port int) *phoneHomeServer {
+	return &phoneHomeServer{
+		kvm:       kvm,
+		port:      port,
+		listeners: make(map[string]string),
+		started:   make(map[string]time.Time),
+		reports:   make(map[string]*phoneHomeReport),
+		// Um lugar por VM: o handler nunca bloqueia, mesmo antes de wait ser chamado
+		arrived: make(chan string, len(kvm.config.VMs)+1),
+	}
+}
+
+// baseURL retorna o URL do listener no endereço do host na bridge, abrindo-o se necessário
+func (s *phoneHomeServer) baseURL(bridge, guestIP string) (string, error) {
+	hostIP, err := bridgeHostIPv4(bridge, guestIP)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if url, ok := s.listeners[hostIP]; ok {
+		return url, nil
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(hostIP, fmt.Sprintf("%d", s.port)))
+	if err != nil {
+		return "", fmt.Errorf("erro ao escutar em %s: %v", hostIP, err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(s.handle), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	s.servers = append(s.servers, server)
+
+	url := "http://" + listener.Addr().String()
+	s.listeners[hostIP] = url
+	color.Cyan("📡 A aguardar phone_home em %s", url)
+	return url, nil
+}
+
+// cloudConfig gera as chaves cloud-init phone_home e final_message da VM
+func (s *phoneHomeServer) cloudConfig(vm *VM) (map[string]interface{}, error) {
+	if len(vm.Networks) == 0 {
+		return nil, fmt.Errorf("VM sem interfaces de rede")
+	}
+	bridge := vm.Networks[0].HostBridge
+	if bridge == "" {
+		bridge = "br0"
+	}
+	url, err := s.baseURL(bridge, vm.Networks[0].GuestIPv4)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.started[vm.Name] = time.Now()
+	s.mu.Unlock()
+
+	return map[string]interface{}{
+		"phone_home": map[string]interface{}{
+			"url":   url + phoneHomePath + vm.Name,
+			"post":  []interface{}{"pub_key_ed25519", "pub_key_ecdsa", "pub_key_rsa", "instance_id", "hostname", "fqdn"},
+			"tries": 10,
+		},
+		"final_message": fmt.Sprintf("kvm-compose: %s pronta após $UPTIME segundos (cloud-init $VERSION)", vm.Name),
+	}, nil
+}
+
+// handle recebe o POST do módulo phone_home do cloud-init
+func (s *phoneHomeServer) handle(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, phoneHomePath)
+	s.mu.Lock()
+	started, expected := s.started[name]
+	s.mu.Unlock()
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, phoneHomePath) || !expected {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := &phoneHomeReport{
+		VM:         name,
+		InstanceID: r.PostForm.Get("instance_id"),
+		Hostname:   r.PostForm.Get("hostname"),
+		FQDN:       r.PostForm.Get("fqdn"),
+		HostKeys:   make(map[string]string),
+		RemoteAddr: r.RemoteAddr,
+		ReadyAt:    time.Now(),
+	}
+	report.Elapsed = report.ReadyAt.Sub(started).Round(time.Second)
+	for _, keyType := range []string{"ed25519", "ecdsa", "rsa"} {
+		// O cloud-init envia "N/A" para os tipos que não existem
+		if key := strings.TrimSpace(r.PostForm.Get("pub_key_" + keyType)); key != "" && key != "N/A" {
+			report.HostKeys[keyType] = key
+		}
+	}
+	report.HostKeysMatch = s.kvm.hostKeysMatch(name, report.HostKeys)
+
+	s.mu.Lock()
+	_, duplicate := s.reports[name]
+	s.reports[name] = report
+	s.mu.Unlock()
+
+	if err := s.kvm.savePhoneHomeReport(report); err != nil {
+		color.Yellow("⚠️  Erro ao gravar report de %s: %v", name, err)
+	}
+	w.WriteHeader(http.StatusOK)
+	if !duplicate {
+		s.arrived <- name
+	}
+}
+
+// wait aguarda os reports das VMs indicadas até ao timeout e retorna os recebidos
+func (s *phoneHomeServer) wait(names []string, timeout time.Duration) map[string]*phoneHomeReport {
+	pending := make(map[string]bool)
+	s.mu.Lock()
+	for _, name := range names {
+		if _, ok := s.reports[name]; !ok {
+			pending[name] = true
+		}
+	}
+	s.mu.Unlock()
+
+	deadline := time.After(timeout)
+	for len(pending) > 0 {
+		select {
+		case name := <-s.arrived:
+			if !pending[name] {
+				continue
+			}
+			delete(pending, name)
+			s.mu.Lock()
+			report := s.reports[name]
+			s.mu.Unlock()
+			color.Green("✅ %s pronta (%s)", name, report.Elapsed)
+			if !report.HostKeysMatch {
+				color.Yellow("⚠️  %s reportou chaves de host diferentes das fixadas pelo kvm-compose", name)
+			}
+		case <-deadline:
+			pending = nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := make(map[string]*phoneHomeReport)
+	for _, name := range names {
+		if report, ok := s.reports[name]; ok {
+			reports[name] = report
+		}
+	}
+	return reports
+}
+
+// close encerra os listeners
+func (s *phoneHomeServer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, server := range s.servers {
+		server.Close()
+	}
+}
+
+// printPhoneHomeSummary mostra a tabela de prontidão das VMs aguardadas
+func printPhoneHomeSummary(names []string, reports map[string]*phoneHomeReport) {
+	sort.Strings(names)
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-12s %-8s %-38s %-12s\n", "Nome", "Estado", "Tempo", "Instance ID", "Chaves host")
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-12s %-8s %-38s %-12s\n",
+		"---------------", "------------", "--------", "--------------------------------------", "------------")
+	for _, name := range names {
+		report, ok := reports[name]
+		if !ok {
+			color.Red("%-15s %-12s %-8s %-38s %-12s", name, "sem resposta", "-", "-", "-")
+			continue
+		}
+		keys := "fixadas"
+		if !report.HostKeysMatch {
+			keys = "diferentes"
+		}
+		fmt.Printf("%-15s %-12s %-8s %-38s %-12s\n", name, "pronta", report.Elapsed, report.InstanceID, keys)
+	}
+}
+
+// hostKeysMatch verifica se as chaves reportadas pela VM são as chaves de host fixadas
+func (kvm *KVMCompose) hostKeysMatch(vmName string, reported map[string]string) bool {
+	keys, err := kvm.vmHostKeys(vmName, false)
+	if err != nil || len(keys) == 0 {
+		return false
+	}
+	for _, key := range keys {
+		if sshKeyBody(reported[key.Type]) != sshKeyBody(key.Public) {
+			return false
+		}
+	}
+	return true
+}
+
+// sshKeyBody retorna o tipo e a chave de uma linha de chave pública, sem o comentário
+func sshKeyBody(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// savePhoneHomeReport grava o report no diretório de trabalho da VM
+func (kvm *KVMCompose) savePhoneHomeReport(report *phoneHomeReport) error {
+	dir, err := kvm.vmWorkDir(report.VM)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, phoneHomeReportName), append(data, '\n'), 0644)
+}
+
+// bridgeHostIPv4 retorna o endereço IPv4 do host na bridge, de preferência na rede do convidado
+func bridgeHostIPv4(bridge, guestIP string) (string, error) {
+	iface, err := net.InterfaceByName(bridge)
+	if err != nil {
+		return "", fmt.Errorf("bridge %s: %v", bridge, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("bridge %s: %v", bridge, err)
+	}
+	guest := net.ParseIP(guestIP)
+	first := ""
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+		if guest != nil && ipNet.Contains(guest) {
+			return ipNet.IP.String(), nil
+		}
+		if first == "" {
+			first = ipNet.IP.String()
+		}
+	}
+	if first == "" {
+		return "", fmt.Errorf("bridge %s sem endereço IPv4 no host", bridge)
+	}
+	return first, nil
+}
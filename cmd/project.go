@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// projectDirName é o nome do diretório de estado do projeto, criado junto ao compose
+const projectDirName = ".kvm-compose"
+
+// projectName retorna o nome do projeto: o campo name do compose ou o nome do diretório do compose
+func (kvm *KVMCompose) projectName() string {
+	name := kvm.config.Name
+	if name == "" {
+		abs, err := filepath.Abs(kvm.composeFile)
+		if err != nil {
+			abs = kvm.composeFile
+		}
+		name = filepath.Base(filepath.Dir(abs))
+	}
+	name = strings.ToLower(name)
+	return regexp.MustCompile(`[^a-z0-9_-]+`).ReplaceAllString(name, "")
+}
+
+// projectDir retorna o diretório de estado do projeto (.kvm-compose ao lado do compose)
+func (kvm *KVMCompose) projectDir() string {
+	abs, err := filepath.Abs(kvm.composeFile)
+	if err != nil {
+		abs = kvm.composeFile
+	}
+	return filepath.Join(filepath.Dir(abs), projectDirName)
+}
+
+// ensureProjectDir cria o diretório de estado do projeto se não existir
+func (kvm *KVMCompose) ensureProjectDir() (string, error) {
+	dir := kvm.projectDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// vmsDirName é o subdiretório do estado do projeto com os diretórios de trabalho das VMs,
+// separado para que o nome de uma VM nunca coincida com outro estado (ex.: hostkeys)
+const vmsDirName = "vms"
+
+// vmWorkDirPath retorna o diretório de trabalho de uma VM (.kvm-compose/vms/<vm>), sem o criar
+func (kvm *KVMCompose) vmWorkDirPath(vmName string) string {
+	return filepath.Join(kvm.projectDir(), vmsDirName, vmName)
+}
+
+// vmWorkDir cria e retorna o diretório de trabalho de uma VM (.kvm-compose/vms/<vm>),
+// onde ficam os ficheiros gerados para ela
+func (kvm *KVMCompose) vmWorkDir(vmName string) (string, error) {
+	dir := kvm.vmWorkDirPath(vmName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Render mostra os ficheiros cloud-init das VMs indicadas sem criar nada: os IPs
+// ainda não alocados são atribuídos apenas em memória e nada é gravado
+func (kvm *KVMCompose) Render(names []string, only string) error {
+	if err := kvm.loadConfig(); err != nil {
+		return err
+	}
+	if err := kvm.resolveNetworks(true, false); err != nil {
+		return err
+	}
+	vms, err := kvm.selectVMs(names)
+	if err != nil {
+		return err
+	}
+
+	failedCount := 0
+	for _, vm := range vms {
+		files, err := kvm.renderCloudInit(&vm, true)
+		if err != nil {
+			color.Red("❌ %v", err)
+			failedCount++
+			continue
+		}
+		for _, file := range files.isoFiles() {
+			if only != "" && file.Name != only {
+				continue
+			}
+			color.Cyan("# ===== %s/%s =====", vm.Name, file.Name)
+			fmt.Print(string(file.Data))
+			if len(file.Data) > 0 && file.Data[len(file.Data)-1] != '\n' {
+				fmt.Println()
+			}
+		}
+	}
+	if failedCount > 0 {
+		return fmt.Errorf("%d VM(s) com erros de renderização", failedCount)
+	}
+	return nil
+}
+
+var renderOnly string
+
+var renderCmd = &cobra.Command{
+	Use:   "render [vm...]",
+	Short: "Mostrar os ficheiros cloud-init renderizados sem criar nada",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.Render(args, renderOnly); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Register render command
+	renderCmd.Flags().StringVar(&renderOnly, "only", "", "Mostrar apenas um ficheiro (user-data, network-config ou meta-data)")
+	rootCmd.AddCommand(renderCmd)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+// seedVolumeID é o rótulo que a datasource NoCloud do cloud-init procura
+const seedVolumeID = "cidata"
+
+// getSeedPath retorna o caminho do seed NoCloud da VM, ao lado do disco
+func (kvm *KVMCompose) getSeedPath(vmName string) string {
+	return filepath.Join(filepath.Dir(kvm.getVMImagePath(vmName)), vmName+"-cidata.iso")
+}
+
+// createSeedISO renderiza o cloud-init da VM, guarda os ficheiros no diretório de trabalho
+// da VM e grava o seed NoCloud (ISO com rótulo cidata). Um seed existente é substituído.
+func (kvm *KVMCompose) createSeedISO(vm *VM) (string, error) {
+	files, err := kvm.renderCloudInit(vm, false)
+	if err != nil {
+		return "", err
+	}
+	// Manter uma cópia do que foi enviado no diretório de trabalho da VM
+	workDir, err := kvm.vmWorkDir(vm.Name)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de trabalho: %v", err)
+	}
+	if err := files.save(workDir); err != nil {
+		return "", fmt.Errorf("erro ao gravar ficheiros cloud-init em %s: %v", workDir, err)
+	}
+	if err := kvm.updateKnownHosts(); err != nil {
+		color.Yellow("⚠️  Erro ao atualizar %s: %v", kvm.knownHostsPath(), err)
+	}
+	seedPath := kvm.getSeedPath(vm.Name)
+
+	if !kvm.usePool() {
+		if err := writeISO(seedPath, seedVolumeID, files.isoFiles()); err != nil {
+			return "", fmt.Errorf("erro ao gravar seed %s: %v", seedPath, err)
+		}
+		color.Cyan("💿 Seed cloud-init: %s", seedPath)
+		return seedPath, nil
+	}
+
+	// No storage pool, o seed é gerado num ficheiro temporário e enviado como volume
+	tmp, err := os.CreateTemp("", "kvm-compose-*-cidata.iso")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := writeISO(tmp.Name(), seedVolumeID, files.isoFiles()); err != nil {
+		return "", fmt.Errorf("erro ao gravar seed: %v", err)
+	}
+	kvm.removeDisk(seedPath)
+	if err := kvm.uploadVolume(tmp.Name(), filepath.Base(seedPath), "raw"); err != nil {
+		return "", err
+	}
+	color.Cyan("💿 Seed cloud-init: volume %s/%s", kvm.appConfig.Images.Pool, filepath.Base(seedPath))
+	return seedPath, nil
+}
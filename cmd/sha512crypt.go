@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"strings"
+)
+
+// Implementação do sha512-crypt ($6$) de Ulrich Drepper, o formato aceite pelo
+// campo passwd do cloud-init e pelo /etc/shadow
+
+const (
+	sha512CryptRounds  = 5000
+	sha512CryptSaltLen = 16
+	cryptAlphabet      = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// sha512CryptOrder é a ordem pela qual os bytes do digest são codificados
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+// hashPassword gera o hash sha512-crypt de uma password com um salt aleatório
+func hashPassword(password string) (string, error) {
+	random := make([]byte, sha512CryptSaltLen)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	salt := make([]byte, sha512CryptSaltLen)
+	for i, b := range random {
+		salt[i] = cryptAlphabet[int(b)%len(cryptAlphabet)]
+	}
+	return sha512Crypt(password, string(salt)), nil
+}
+
+// isPasswordHash indica se o valor já é um hash crypt (ex.: $6$...), a usar sem alterações
+func isPasswordHash(value string) bool {
+	for _, prefix := range []string{"$1$", "$5$", "$6$", "$y$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sha512Crypt calcula o hash sha512-crypt com o salt indicado e o número de rondas padrão
+func sha512Crypt(password, salt string) string {
+	pw := []byte(password)
+	if len(salt) > sha512CryptSaltLen {
+		salt = salt[:sha512CryptSaltLen]
+	}
+	s := []byte(salt)
+
+	// Digest B: password + salt + password
+	hb := sha512.New()
+	hb.Write(pw)
+	hb.Write(s)
+	hb.Write(pw)
+	b := hb.Sum(nil)
+
+	// Digest A
+	ha := sha512.New()
+	ha.Write(pw)
+	ha.Write(s)
+	i := len(pw)
+	for ; i > 64; i -= 64 {
+		ha.Write(b)
+	}
+	ha.Write(b[:i])
+	for i = len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ha.Write(b)
+		} else {
+			ha.Write(pw)
+		}
+	}
+	a := ha.Sum(nil)
+
+	// Sequência P: a password repetida
+	hp := sha512.New()
+	for range pw {
+		hp.Write(pw)
+	}
+	dp := hp.Sum(nil)
+	p := make([]byte, 0, len(pw))
+	for i = len(pw); i > 64; i -= 64 {
+		p = append(p, dp...)
+	}
+	p = append(p, dp[:i]...)
+
+	// Sequência S: o salt repetido 16+A[0] vezes
+	hs := sha512.New()
+	for i = 0; i < 16+int(a[0]); i++ {
+		hs.Write(s)
+	}
+	ds := hs.Sum(nil)
+	sseq := ds[:len(s)]
+
+	// Rondas
+	for r := 0; r < sha512CryptRounds; r++ {
+		hc := sha512.New()
+		if r&1 != 0 {
+			hc.Write(p)
+		} else {
+			hc.Write(a)
+		}
+		if r%3 != 0 {
+			hc.Write(sseq)
+		}
+		if r%7 != 0 {
+			hc.Write(p)
+		}
+		if r&1 != 0 {
+			hc.Write(a)
+		} else {
+			hc.Write(p)
+		}
+		a = hc.Sum(nil)
+	}
+
+	// Codificação no alfabeto do crypt
+	var out strings.Builder
+	out.WriteString("$6$")
+	out.WriteString(salt)
+	out.WriteString("$")
+	encode := func(b2, b1, b0 byte, n int) {
+		w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for ; n > 0; n-- {
+			out.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for _, o := range sha512CryptOrder {
+		encode(a[o[0]], a[o[1]], a[o[2]], 4)
+	}
+	encode(0, 0, a[63], 2)
+	return out.String()
+}
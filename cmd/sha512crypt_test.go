@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSHA512Crypt(t *testing.T) {
+	// Resultados de "openssl passwd -6 -salt <salt> <password>"
+	tests := []struct {
+		password, salt, want string
+	}{
+		{"Hello world!", "saltstring", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"Hello", "saltstring", "$6$saltstring$aQzKv7HhksN4CNT5HySRdxOEHxZvlWWP2je/lOgbrHx5iLYj3NJfVnC287n/dwkODYWL1.LZUdO9vX84fkCna/"},
+		{"p", "toolongsaltstringXYZ", "$6$toolongsaltstrin$MmmlhWAKxDH5xeX17gZ8VFDDX1fhyfFRJyG6cxzsb8xGmRlgGtxkGi/m17UfXa4aPhqCEjSN4H1ia4wcjrDlh."},
+		{"pässwörd", "abc", "$6$abc$wXvLgMChht3U87i888StiQy1W/EK2Ge3H6rxIRM8l0prwhG1icmLmgEOcYMiXbSth/t5JyRjjVGbqvVEQ7j9n/"},
+		{"uma password bem mais comprida do que sessenta e quatro caracteres para testar os blocos", "x/Y.9",
+			"$6$x/Y.9$IHPrJCtbYq/S8wrJC2bGxOXU8OOQ0OxWbtZzHczDCjRX2f8Jpo/1yS4DHX0EAY9GH.SQZ/Btnbp62DoVl5kcK/"},
+	}
+	for _, tt := range tests {
+		if got := sha512Crypt(tt.password, tt.salt); got != tt.want {
+			t.Errorf("sha512Crypt(%q, %q) = %s, esperado %s", tt.password, tt.salt, got, tt.want)
+		}
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := hashPassword("segredo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[1] != "6" || len(parts[2]) != sha512CryptSaltLen {
+		t.Fatalf("formato inesperado: %s", hash)
+	}
+	if sha512Crypt("segredo", parts[2]) != hash {
+		t.Error("o hash não é reproduzível com o salt gerado")
+	}
+	if other, _ := hashPassword("segredo"); other == hash {
+		t.Error("dois hashes com o mesmo salt")
+	}
+	if !isPasswordHash(hash) || isPasswordHash("segredo") {
+		t.Error("isPasswordHash não reconhece o formato crypt")
+	}
+}
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// currentSnapshot retorna o snapshot atual de uma VM, ou "" se não houver
+func currentSnapshot(name string) string {
+	snapshot, err := execCommandOutput("virsh", "snapshot-current", "--domain", name, "--name")
+	if err != nil {
+		return ""
+	}
+	return snapshot
+}
+
+// listSnapshots retorna os nomes dos snapshots de uma VM
+func listSnapshots(name string) []string {
+	output, err := execCommandOutput("virsh", "snapshot-list", "--domain", name, "--name")
+	if err != nil {
+		return nil
+	}
+	return parseSnapshotNames(output)
+}
+
+// parseSnapshotNames extrai os nomes da saída de "virsh snapshot-list --name" (um por linha)
+func parseSnapshotNames(output string) []string {
+	var snapshots []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			snapshots = append(snapshots, line)
+		}
+	}
+	return snapshots
+}
+
+// snapshotCreateArgs retorna os argumentos do virsh para criar um snapshot interno
+func snapshotCreateArgs(vmName, snapshot, description string) []string {
+	return []string{"snapshot-create-as", "--domain", vmName, "--name", snapshot, "--description", description, "--atomic"}
+}
+
+// snapshotDomainXML representa os campos de "virsh dumpxml" que impedem snapshots internos
+type snapshotDomainXML struct {
+	OS struct {
+		Loader struct {
+			Type string `xml:"type,attr"`
+		} `xml:"loader"`
+	} `xml:"os"`
+	Disks []struct {
+		Device string `xml:"device,attr"`
+		Driver struct {
+			Type string `xml:"type,attr"`
+		} `xml:"driver"`
+		Source struct {
+			File   string `xml:"file,attr"`
+			Volume string `xml:"volume,attr"`
+		} `xml:"source"`
+		Target struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"target"`
+		ReadOnly *struct{} `xml:"readonly"`
+	} `xml:"devices>disk"`
+}
+
+// internalSnapshotBlockers retorna as razões pelas quais o domínio não suporta snapshots
+// internos (snapshot-create-as sem --disk-only): firmware UEFI em pflash ou discos que não são qcow2
+func internalSnapshotBlockers(domainXML string) ([]string, error) {
+	var domain snapshotDomainXML
+	if err := xml.Unmarshal([]byte(domainXML), &domain); err != nil {
+		return nil, fmt.Errorf("erro ao ler XML do domínio: %v", err)
+	}
+	var blockers []string
+	if domain.OS.Loader.Type == "pflash" {
+		blockers = append(blockers, "firmware UEFI (pflash)")
+	}
+	for _, disk := range domain.Disks {
+		if disk.Device != "disk" || disk.ReadOnly != nil || disk.Driver.Type == "qcow2" {
+			continue
+		}
+		source := disk.Source.File
+		if source == "" {
+			source = disk.Source.Volume
+		}
+		blockers = append(blockers, fmt.Sprintf("disco %s (%s) em formato %s", disk.Target.Dev, filepath.Base(source), disk.Driver.Type))
+	}
+	return blockers, nil
+}
+
+// checkInternalSnapshot verifica antes do snapshot se a VM suporta snapshots internos
+func checkInternalSnapshot(vmName string) error {
+	dump, err := execCommandOutput("virsh", "dumpxml", vmName)
+	if err != nil {
+		return fmt.Errorf("erro ao obter XML da VM: %v", err)
+	}
+	blockers, err := internalSnapshotBlockers(dump)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return fmt.Errorf("snapshots internos não suportados: %s", strings.Join(blockers, ", "))
+	}
+	return nil
+}
+
+// snapshotAction executa uma ação de snapshot em todas as VMs selecionadas. Com precheck,
+// todas as VMs são verificadas antes e nada é feito se alguma falhar, para que o grupo
+// não fique com o snapshot apenas em parte das VMs.
+func (kvm *KVMCompose) snapshotAction(title string, names []string, precheck, action func(vm VM) error) error {
+	if err := kvm.loadConfig(); err != nil {
+		return err
+	}
+	vms, err := kvm.selectVMs(names)
+	if err != nil {
+		return err
+	}
+
+	color.Cyan("=== %s ===", title)
+	if precheck != nil {
+		blocked := 0
+		for _, vm := range vms {
+			if !vmExists(vm.Name) {
+				continue
+			}
+			if err := precheck(vm); err != nil {
+				color.Red("❌ %s: %v", vm.Name, err)
+				blocked++
+			}
+		}
+		if blocked > 0 {
+			return fmt.Errorf("%d VM(s) não suportam a operação; nenhuma VM foi alterada", blocked)
+		}
+	}
+	okCount := 0
+	failedCount := 0
+	for _, vm := range vms {
+		if !vmExists(vm.Name) {
+			color.Yellow("⚠️  VM %s não existe.", vm.Name)
+			failedCount++
+			continue
+		}
+		if err := action(vm); err != nil {
+			color.Red("❌ %s: %v", vm.Name, err)
+			failedCount++
+			continue
+		}
+		okCount++
+	}
+
+	fmt.Println()
+	color.Cyan("=== Resumo ===")
+	fmt.Printf("VMs processadas: %d\n", okCount)
+	fmt.Printf("VMs com falha: %d\n", failedCount)
+	if failedCount > 0 {
+		return fmt.Errorf("%d VM(s) com falha", failedCount)
+	}
+	return nil
+}
+
+// SnapshotCreate cria um snapshot com o mesmo nome em todas as VMs selecionadas
+func (kvm *KVMCompose) SnapshotCreate(snapshot string, names []string) error {
+	precheck := func(vm VM) error { return checkInternalSnapshot(vm.Name) }
+	return kvm.snapshotAction(fmt.Sprintf("Criando snapshot %s", snapshot), names, precheck, func(vm VM) error {
+		description := fmt.Sprintf("kvm-compose %s: %s", kvm.projectName(), snapshot)
+		if err := execCommand("virsh", snapshotCreateArgs(vm.Name, snapshot, description)...); err != nil {
+			return err
+		}
+		color.Green("📸 Snapshot %s criado em %s", snapshot, vm.Name)
+		return nil
+	})
+}
+
+// SnapshotRevert reverte todas as VMs selecionadas para o snapshot indicado
+func (kvm *KVMCompose) SnapshotRevert(snapshot string, names []string) error {
+	return kvm.snapshotAction(fmt.Sprintf("Revertendo para o snapshot %s", snapshot), names, nil, func(vm VM) error {
+		if err := execCommand("virsh", "snapshot-revert", "--domain", vm.Name, "--snapshotname", snapshot); err != nil {
+			return err
+		}
+		color.Green("⏪ VM %s revertida para %s", vm.Name, snapshot)
+		return nil
+	})
+}
+
+// SnapshotDelete remove o snapshot indicado de todas as VMs selecionadas
+func (kvm *KVMCompose) SnapshotDelete(snapshot string, names []string) error {
+	return kvm.snapshotAction(fmt.Sprintf("Removendo snapshot %s", snapshot), names, nil, func(vm VM) error {
+		if err := execCommand("virsh", "snapshot-delete", "--domain", vm.Name, "--snapshotname", snapshot); err != nil {
+			return err
+		}
+		color.Green("🗑️  Snapshot %s removido de %s", snapshot, vm.Name)
+		return nil
+	})
+}
+
+// SnapshotList lista os snapshots das VMs selecionadas
+func (kvm *KVMCompose) SnapshotList(names []string) error {
+	if err := kvm.loadConfig(); err != nil {
+		return err
+	}
+	vms, err := kvm.selectVMs(names)
+	if err != nil {
+		return err
+	}
+
+	color.Cyan("=== Snapshots das VMs do %s ===", kvm.composeFile)
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-20s %-8s\n", "Nome", "Snapshot", "Atual")
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-20s %-8s\n",
+		strings.Repeat("-", 15), strings.Repeat("-", 20), strings.Repeat("-", 8))
+	for _, vm := range vms {
+		if !vmExists(vm.Name) {
+			fmt.Printf("%-15s %-20s %-8s\n", vm.Name, "(not created)", "")
+			continue
+		}
+		snapshots := listSnapshots(vm.Name)
+		if len(snapshots) == 0 {
+			fmt.Printf("%-15s %-20s %-8s\n", vm.Name, "-", "")
+			continue
+		}
+		current := currentSnapshot(vm.Name)
+		for _, snapshot := range snapshots {
+			marker := ""
+			if snapshot == current {
+				marker = "✔"
+			}
+			fmt.Printf("%-15s %-20s %-8s\n", vm.Name, snapshot, marker)
+		}
+	}
+	return nil
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Gerir snapshots das VMs do compose",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <nome> [vm...]",
+	Short: "Criar um snapshot em todas as VMs (ou nas indicadas)",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.SnapshotCreate(args[0], args[1:]); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list [vm...]",
+	Short: "Listar os snapshots das VMs",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.SnapshotList(args); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotRevertCmd = &cobra.Command{
+	Use:   "revert <nome> [vm...]",
+	Short: "Reverter todas as VMs (ou as indicadas) para um snapshot",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.SnapshotRevert(args[0], args[1:]); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <nome> [vm...]",
+	Short: "Remover um snapshot de todas as VMs (ou das indicadas)",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.SnapshotDelete(args[0], args[1:]); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Register snapshot command
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRevertCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSnapshotNames(t *testing.T) {
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{"", nil},
+		{"\n\n", nil},
+		{"antes-upgrade\n", []string{"antes-upgrade"}},
+		{" base \n\nantes-upgrade\r\n", []string{"base", "antes-upgrade"}},
+	}
+	for _, tt := range tests {
+		if got := parseSnapshotNames(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSnapshotNames(%q) = %v, esperado %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotCreateArgs(t *testing.T) {
+	got := snapshotCreateArgs("web", "base", "kvm-compose lab: base")
+	want := []string{"snapshot-create-as", "--domain", "web", "--name", "base", "--description", "kvm-compose lab: base", "--atomic"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshotCreateArgs = %v, esperado %v", got, want)
+	}
+}
+
+// domainXML monta um XML de domínio mínimo com o loader e os discos indicados
+func domainXML(loader string, disks ...string) string {
+	return "<domain type='kvm'><name>web</name><os><type>hvm</type>" + loader + "</os><devices>" +
+		strings.Join(disks, "") + "</devices></domain>"
+}
+
+func TestInternalSnapshotBlockers(t *testing.T) {
+	root := `<disk type='file' device='disk'><driver name='qemu' type='qcow2'/><source file='/var/lib/libvirt/images/web.qcow2'/><target dev='vda' bus='virtio'/></disk>`
+	seed := `<disk type='file' device='cdrom'><driver name='qemu' type='raw'/><source file='/var/lib/libvirt/images/web-cidata.iso'/><target dev='sda' bus='sata'/><readonly/></disk>`
+	raw := `<disk type='file' device='disk'><driver name='qemu' type='raw'/><source file='/var/lib/libvirt/images/web-dados.raw'/><target dev='vdb' bus='virtio'/></disk>`
+	pflash := `<loader readonly='yes' type='pflash'>/usr/share/OVMF/OVMF_CODE.fd</loader><nvram>/var/lib/libvirt/qemu/nvram/web_VARS.fd</nvram>`
+
+	tests := []struct {
+		name string
+		xml  string
+		want []string
+	}{
+		{"qcow2 e seed", domainXML("", root, seed), nil},
+		{"disco raw", domainXML("", root, seed, raw), []string{"disco vdb (web-dados.raw) em formato raw"}},
+		{"uefi", domainXML(pflash, root), []string{"firmware UEFI (pflash)"}},
+		{"uefi e raw", domainXML(pflash, root, raw), []string{"firmware UEFI (pflash)", "disco vdb (web-dados.raw) em formato raw"}},
+	}
+	for _, tt := range tests {
+		got, err := internalSnapshotBlockers(tt.xml)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := internalSnapshotBlockers("<domain"); err == nil {
+		t.Error("esperado erro com XML inválido")
+	}
+}
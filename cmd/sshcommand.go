@@ -47,7 +47,21 @@ var sshCmd = &cobra.Command{
 		target := fmt.Sprintf("%s@%s", user, vm.Networks[0].GuestIPv4)
 		color.Cyan("🔗 SSH to %s", target)
 
-		argsToPass := append([]string{target}, extra...)
+		argsToPass := []string{target}
+		// Chave gerada pelo kvm-compose para as VMs sem chaves SSH configuradas
+		keyPath := kvm.projectKeyPath()
+		if _, err := os.Stat(keyPath); err == nil {
+			argsToPass = append([]string{"-i", keyPath}, argsToPass...)
+		}
+		// Chaves de host fixas do projeto: verificação estrita, sem tocar no ~/.ssh/known_hosts.
+		// VMs sem chave fixada (ex.: criadas antes das chaves de host) aceitam a primeira chave.
+		knownHosts := kvm.knownHostsPath()
+		if knownHostsHas(knownHosts, vm.Networks[0].GuestIPv4) {
+			argsToPass = append([]string{"-o", "UserKnownHostsFile=" + knownHosts, "-o", "StrictHostKeyChecking=yes"}, argsToPass...)
+		} else {
+			argsToPass = append([]string{"-o", "StrictHostKeyChecking=accept-new"}, argsToPass...)
+		}
+		argsToPass = append(argsToPass, extra...)
 		if err := runInteractiveCommand("ssh", argsToPass...); err != nil {
 			color.Red("Erro ao executar ssh: %v", err)
 			os.Exit(1)
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// projectKeyName é o nome do par de chaves SSH gerado para o projeto no diretório de estado
+const projectKeyName = "id_ed25519"
+
+// projectKeyPath retorna o caminho da chave privada SSH do projeto
+func (kvm *KVMCompose) projectKeyPath() string {
+	return filepath.Join(kvm.projectDir(), projectKeyName)
+}
+
+// projectPublicKey retorna a chave pública SSH do projeto. Com create, o par de chaves
+// é gerado se ainda não existir; sem create, retorna "" se não existir.
+func (kvm *KVMCompose) projectPublicKey(create bool) (string, error) {
+	keyPath := kvm.projectKeyPath()
+	if key, err := readSSHKey(keyPath + ".pub"); err == nil {
+		return key, nil
+	}
+	if !create {
+		return "", nil
+	}
+	if _, err := kvm.ensureProjectDir(); err != nil {
+		return "", err
+	}
+
+	comment := fmt.Sprintf("kvm-compose@%s", kvm.projectName())
+	privateKey, publicKey, err := generateSSHKey(comment)
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar chave SSH do projeto: %v", err)
+	}
+	if err := os.WriteFile(keyPath, privateKey, 0600); err != nil {
+		return "", fmt.Errorf("erro ao gravar %s: %v", keyPath, err)
+	}
+	if err := os.WriteFile(keyPath+".pub", []byte(publicKey+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("erro ao gravar %s.pub: %v", keyPath, err)
+	}
+	color.Green("🔑 Chave SSH do projeto gerada: %s", keyPath)
+	return publicKey, nil
+}
+
+// generateSSHKey gera um par de chaves ed25519: a privada no formato OpenSSH e a pública
+// no formato authorized_keys
+func generateSSHKey(comment string) ([]byte, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	publicKey := sshString(nil, []byte("ssh-ed25519"))
+	publicKey = sshString(publicKey, pub)
+
+	// Secção privada: checkint repetido, chave, comentário e padding até múltiplo de 8
+	check := make([]byte, 4)
+	if _, err := rand.Read(check); err != nil {
+		return nil, "", err
+	}
+	private := append(append([]byte{}, check...), check...)
+	private = sshString(private, []byte("ssh-ed25519"))
+	private = sshString(private, pub)
+	private = sshString(private, priv)
+	private = sshString(private, []byte(comment))
+	for i := byte(1); len(private)%8 != 0; i++ {
+		private = append(private, i)
+	}
+
+	key := []byte("openssh-key-v1\x00")
+	key = sshString(key, []byte("none")) // cifra
+	key = sshString(key, []byte("none")) // kdf
+	key = sshString(key, nil)            // opções do kdf
+	key = binary.BigEndian.AppendUint32(key, 1)
+	key = sshString(key, publicKey)
+	key = sshString(key, private)
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: key})
+	authorized := strings.Join([]string{"ssh-ed25519", base64.StdEncoding.EncodeToString(publicKey), comment}, " ")
+	return privatePEM, authorized, nil
+}
+
+// sshString acrescenta um campo string do protocolo SSH (tamanho uint32 + dados)
+func sshString(buf, data []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readSSHString lê um campo string do protocolo SSH e retorna o resto do buffer
+func readSSHString(t *testing.T, buf []byte) ([]byte, []byte) {
+	t.Helper()
+	if len(buf) < 4 {
+		t.Fatalf("campo SSH truncado")
+	}
+	n := binary.BigEndian.Uint32(buf)
+	if uint32(len(buf)-4) < n {
+		t.Fatalf("campo SSH com tamanho %d maior que o buffer", n)
+	}
+	return buf[4 : 4+n], buf[4+n:]
+}
+
+// authorizedKeyBlob valida uma linha authorized_keys e retorna o blob da chave
+func authorizedKeyBlob(t *testing.T, line, keyType, comment string) []byte {
+	t.Helper()
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != keyType || fields[2] != comment {
+		t.Fatalf("chave pública %q, esperado '%s <base64> %s'", line, keyType, comment)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatalf("base64 inválido na chave pública: %v", err)
+	}
+	return blob
+}
+
+func TestGenerateSSHKey(t *testing.T) {
+	privatePEM, public, err := generateSSHKey("kvm-compose@lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := authorizedKeyBlob(t, public, "ssh-ed25519", "kvm-compose@lab")
+
+	block, rest := pem.Decode(privatePEM)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" || len(bytes.TrimSpace(rest)) != 0 {
+		t.Fatalf("PEM inválido: %q", privatePEM)
+	}
+	magic := []byte("openssh-key-v1\x00")
+	if !bytes.HasPrefix(block.Bytes, magic) {
+		t.Fatalf("esperado prefixo openssh-key-v1")
+	}
+	buf := block.Bytes[len(magic):]
+	var cipher, kdf, kdfOptions, publicBlob, private []byte
+	cipher, buf = readSSHString(t, buf)
+	kdf, buf = readSSHString(t, buf)
+	kdfOptions, buf = readSSHString(t, buf)
+	if string(cipher) != "none" || string(kdf) != "none" || len(kdfOptions) != 0 {
+		t.Errorf("cifra/kdf = %q/%q, esperado none/none", cipher, kdf)
+	}
+	if n := binary.BigEndian.Uint32(buf); n != 1 {
+		t.Errorf("número de chaves = %d, esperado 1", n)
+	}
+	publicBlob, buf = readSSHString(t, buf[4:])
+	private, buf = readSSHString(t, buf)
+	if len(buf) != 0 {
+		t.Errorf("%d bytes a mais no fim da chave", len(buf))
+	}
+	if !bytes.Equal(publicBlob, blob) {
+		t.Errorf("chave pública do PEM difere da linha authorized_keys")
+	}
+
+	if len(private)%8 != 0 {
+		t.Errorf("secção privada com %d bytes, esperado múltiplo de 8", len(private))
+	}
+	if !bytes.Equal(private[0:4], private[4:8]) {
+		t.Errorf("checkints diferentes: %x %x", private[0:4], private[4:8])
+	}
+	var keyType, pub, priv, comment []byte
+	keyType, private = readSSHString(t, private[8:])
+	pub, private = readSSHString(t, private)
+	priv, private = readSSHString(t, private)
+	comment, private = readSSHString(t, private)
+	if string(keyType) != "ssh-ed25519" || string(comment) != "kvm-compose@lab" {
+		t.Errorf("tipo/comentário = %q/%q", keyType, comment)
+	}
+	for i, b := range private {
+		if b != byte(i+1) {
+			t.Errorf("padding inválido: %v", private)
+			break
+		}
+	}
+	if len(priv) != ed25519.PrivateKeySize || !bytes.Equal(ed25519.PrivateKey(priv).Public().(ed25519.PublicKey), pub) {
+		t.Fatalf("chave privada não corresponde à pública")
+	}
+	wantBlob := sshString(sshString(nil, []byte("ssh-ed25519")), pub)
+	if !bytes.Equal(blob, wantBlob) {
+		t.Errorf("blob da chave pública não corresponde à chave privada")
+	}
+
+	msg := []byte("kvm-compose")
+	if !ed25519.Verify(pub, msg, ed25519.Sign(priv, msg)) {
+		t.Errorf("assinatura com a chave gerada não verifica")
+	}
+}
+
+func TestGenerateSSHKeyWithSSHKeygen(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen não disponível")
+	}
+	privatePEM, public, err := generateSSHKey("kvm-compose@lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), projectKeyName)
+	if err := os.WriteFile(path, privatePEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("ssh-keygen", "-y", "-f", path).Output()
+	if err != nil {
+		t.Fatalf("ssh-keygen não aceitou a chave: %v", err)
+	}
+	if sshKeyBody(string(out)) != sshKeyBody(public) {
+		t.Errorf("ssh-keygen derivou %q, esperado %q", out, public)
+	}
+}
+
+func TestGenerateHostKeyECDSA(t *testing.T) {
+	privatePEM, public, err := generateHostKey("ecdsa", "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(public, []byte("\n")) {
+		t.Errorf("chave pública sem newline final")
+	}
+	blob := authorizedKeyBlob(t, string(public), "ecdsa-sha2-nistp256", "web-01")
+
+	block, _ := pem.Decode(privatePEM)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		t.Fatalf("PEM inválido: %q", privatePEM)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("chave privada inválida: %v", err)
+	}
+
+	keyType, rest := readSSHString(t, blob)
+	curve, rest := readSSHString(t, rest)
+	point, rest := readSSHString(t, rest)
+	if string(keyType) != "ecdsa-sha2-nistp256" || string(curve) != "nistp256" || len(rest) != 0 {
+		t.Fatalf("blob ecdsa inválido: tipo %q, curva %q", keyType, curve)
+	}
+	// Ponto não comprimido: 0x04 || X || Y, com 32 bytes cada na P-256
+	want := make([]byte, 65)
+	want[0] = 4
+	key.PublicKey.X.FillBytes(want[1:33])
+	key.PublicKey.Y.FillBytes(want[33:])
+	if !bytes.Equal(point, want) {
+		t.Errorf("chave pública não corresponde à privada")
+	}
+}
+
+func TestGenerateHostKeyUnknownType(t *testing.T) {
+	if _, _, err := generateHostKey("dsa", "web-01"); err == nil {
+		t.Errorf("esperado erro para tipo de chave desconhecido")
+	}
+}
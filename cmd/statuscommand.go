@@ -19,7 +19,7 @@ func (kvm *KVMCompose) List() error {
 	fmt.Println()
 	color.Cyan("=== VMs disponíveis no %s ===", kvm.composeFile)
 
-	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s\n",
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s %-16s\n",
 		strings.Repeat("-", 15),
 		strings.Repeat("-", 15),
 		strings.Repeat("-", 10),
@@ -27,9 +27,10 @@ func (kvm *KVMCompose) List() error {
 		strings.Repeat("-", 8),
 		strings.Repeat("-", 16),
 		strings.Repeat("-", 16),
-		strings.Repeat("-", 18))
-	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s\n", "Nome", "Distro", "Memória", "vCPUs", "Disco", "Username", "IP", "Status")
-	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s\n",
+		strings.Repeat("-", 18),
+		strings.Repeat("-", 16))
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s %-16s\n", "Nome", "Distro", "Memória", "vCPUs", "Disco", "Username", "IP", "Status", "Snapshot")
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s %-16s\n",
 		strings.Repeat("-", 15),
 		strings.Repeat("-", 15),
 		strings.Repeat("-", 10),
@@ -37,7 +38,8 @@ func (kvm *KVMCompose) List() error {
 		strings.Repeat("-", 8),
 		strings.Repeat("-", 16),
 		strings.Repeat("-", 16),
-		strings.Repeat("-", 18))
+		strings.Repeat("-", 18),
+		strings.Repeat("-", 16))
 
 	for _, vm := range kvm.config.VMs {
 		// Aplicar valores padrão
@@ -62,11 +64,18 @@ func (kvm *KVMCompose) List() error {
 		ip := "N/A"
 		if len(vm.Networks) > 0 {
 			ip = vm.Networks[0].GuestIPv4
+			if ip == "" {
+				ip = "auto"
+			}
 		}
 
 		// Verificar status
 		statusText := "⚪ not created"
+		snapshot := "-"
 		if vmExists(vm.Name) {
+			if current := currentSnapshot(vm.Name); current != "" {
+				snapshot = current
+			}
 			state, _ := getVMState(vm.Name)
 			switch state {
 			case "running":
@@ -87,8 +96,8 @@ func (kvm *KVMCompose) List() error {
 		vcpusStr := fmt.Sprintf("%d", vcpus)
 		diskStr := fmt.Sprintf("%dGB", diskSize)
 
-		fmt.Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s\n",
-			vm.Name, vm.Distro, memoryStr, vcpusStr, diskStr, user, ip, statusText)
+		fmt.Printf("%-15s %-15s %-10s %-6s %-8s %-16s %-16s %-18s %-16s\n",
+			vm.Name, vmImageName(&vm), memoryStr, vcpusStr, diskStr, user, ip, statusText, snapshot)
 	}
 
 	return nil
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// usePool indica se os discos são geridos num storage pool do libvirt ([images] pool)
+func (kvm *KVMCompose) usePool() bool {
+	return kvm.appConfig.Images.Pool != ""
+}
+
+// volumeExists verifica se um volume existe no storage pool
+func volumeExists(pool, name string) bool {
+	_, err := execCommandOutput("virsh", "vol-info", "--pool", pool, name)
+	return err == nil
+}
+
+// volumePath retorna o caminho de um volume do storage pool
+func volumePath(pool, name string) (string, error) {
+	return execCommandOutput("virsh", "vol-path", "--pool", pool, name)
+}
+
+// diskSource retorna a origem do disco para o --disk do virt-install
+func (kvm *KVMCompose) diskSource(path string) string {
+	if kvm.usePool() {
+		return fmt.Sprintf("vol=%s/%s", kvm.appConfig.Images.Pool, filepath.Base(path))
+	}
+	return "path=" + path
+}
+
+// ensureBaseVolume envia a imagem base em cache para o storage pool, se ainda não estiver lá
+func (kvm *KVMCompose) ensureBaseVolume(baseImagePath string) error {
+	pool := kvm.appConfig.Images.Pool
+	name := filepath.Base(baseImagePath)
+	if volumeExists(pool, name) {
+		return nil
+	}
+
+	format, err := imageFormat(baseImagePath)
+	if err != nil {
+		return fmt.Errorf("erro ao obter formato de %s: %v", baseImagePath, err)
+	}
+
+	color.Cyan("📤 Enviando imagem base %s para o pool %s...", name, pool)
+	return kvm.uploadVolume(baseImagePath, name, format)
+}
+
+// uploadVolume cria um volume no storage pool com o conteúdo de um ficheiro local
+func (kvm *KVMCompose) uploadVolume(path, name, format string) error {
+	pool := kvm.appConfig.Images.Pool
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := execCommand("virsh", "vol-create-as", pool, name, fmt.Sprintf("%d", info.Size()), "--format", format); err != nil {
+		return fmt.Errorf("erro ao criar volume %s: %v", name, err)
+	}
+	if err := execCommand("virsh", "vol-upload", "--pool", pool, name, path); err != nil {
+		execCommand("virsh", "vol-delete", "--pool", pool, name)
+		return fmt.Errorf("erro ao enviar %s para o volume %s: %v", path, name, err)
+	}
+	return nil
+}
+
+// removeBaseVolume remove o volume de uma imagem base do storage pool, se existir
+func (kvm *KVMCompose) removeBaseVolume(name string) error {
+	if !kvm.usePool() {
+		return nil
+	}
+	pool := kvm.appConfig.Images.Pool
+	if !volumeExists(pool, name) {
+		return nil
+	}
+	if err := execCommand("virsh", "vol-delete", "--pool", pool, name); err != nil {
+		return fmt.Errorf("erro ao remover volume %s: %v", name, err)
+	}
+	return nil
+}
+
+// createPoolVMDisk cria o disco raiz da VM como volume do storage pool
+func (kvm *KVMCompose) createPoolVMDisk(vm *VM, baseImagePath, vmImagePath string) error {
+	pool := kvm.appConfig.Images.Pool
+	if err := kvm.ensureBaseVolume(baseImagePath); err != nil {
+		return err
+	}
+	baseVolume := filepath.Base(baseImagePath)
+	vmVolume := filepath.Base(vmImagePath)
+	size := fmt.Sprintf("%dG", vm.DiskSize)
+
+	switch vm.DiskMode {
+	case "clone":
+		baseFormat, err := imageFormat(baseImagePath)
+		if err != nil {
+			return fmt.Errorf("erro ao obter formato de %s: %v", baseImagePath, err)
+		}
+		color.Cyan("🧬 Criando clone no pool %s: %s → %s", pool, baseVolume, vmVolume)
+		if err := execCommand("virsh", "vol-create-as", pool, vmVolume, size, "--format", "qcow2",
+			"--backing-vol", baseVolume, "--backing-vol-format", baseFormat); err != nil {
+			return fmt.Errorf("erro ao criar clone: %v", err)
+		}
+	case "copy":
+		color.Cyan("📋 Clonando volume no pool %s: %s → %s", pool, baseVolume, vmVolume)
+		if err := execCommand("virsh", "vol-clone", "--pool", pool, baseVolume, vmVolume); err != nil {
+			return fmt.Errorf("erro ao clonar volume: %v", err)
+		}
+		color.Cyan("🔧 Redimensionando volume %s para %s...", vmVolume, size)
+		if err := execCommand("virsh", "vol-resize", "--pool", pool, vmVolume, size); err != nil {
+			return fmt.Errorf("erro ao redimensionar volume: %v", err)
+		}
+	default:
+		return fmt.Errorf("disk_mode inválido '%s' (use clone ou copy)", vm.DiskMode)
+	}
+	return nil
+}
+
+// createPoolDataDisk cria um disco de dados como volume do storage pool, se ainda não existir
+func (kvm *KVMCompose) createPoolDataDisk(d dataDisk) error {
+	pool := kvm.appConfig.Images.Pool
+	name := filepath.Base(d.Path)
+	if volumeExists(pool, name) {
+		return nil
+	}
+	if err := execCommand("virsh", "vol-create-as", pool, name, fmt.Sprintf("%dG", d.Size), "--format", d.Format); err != nil {
+		return fmt.Errorf("erro ao criar volume %s: %v", name, err)
+	}
+	return nil
+}
+
+// removeDisk remove um disco (ficheiro ou volume do pool). Retorna true se algo foi removido.
+func (kvm *KVMCompose) removeDisk(path string) bool {
+	if kvm.usePool() {
+		pool := kvm.appConfig.Images.Pool
+		name := filepath.Base(path)
+		if !volumeExists(pool, name) {
+			return false
+		}
+		if err := execCommand("virsh", "vol-delete", "--pool", pool, name); err != nil {
+			color.Red("❌ Falha ao remover volume %s: %v", name, err)
+			return false
+		}
+		return true
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// poolVolumeXML representa os campos usados de "virsh vol-dumpxml"
+type poolVolumeXML struct {
+	Target struct {
+		Path string `xml:"path"`
+	} `xml:"target"`
+	BackingStore struct {
+		Path string `xml:"path"`
+	} `xml:"backingStore"`
+}
+
+// poolBaseImageUsers retorna, para cada volume base do pool, os volumes que o usam como backing
+func (kvm *KVMCompose) poolBaseImageUsers() map[string][]string {
+	users := make(map[string][]string)
+	pool := kvm.appConfig.Images.Pool
+	output, err := execCommandOutput("virsh", "vol-list", "--pool", pool, "--name")
+	if err != nil {
+		return users
+	}
+	for _, name := range strings.Split(output, "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		dump, err := execCommandOutput("virsh", "vol-dumpxml", "--pool", pool, name)
+		if err != nil {
+			continue
+		}
+		var volume poolVolumeXML
+		if err := xml.Unmarshal([]byte(dump), &volume); err != nil || volume.BackingStore.Path == "" {
+			continue
+		}
+		users[volume.BackingStore.Path] = append(users[volume.BackingStore.Path], volume.Target.Path)
+	}
+	return users
+}
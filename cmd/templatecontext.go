@@ -0,0 +1,116 @@
+package cmd
+
+// templatePeer representa outra VM do compose no contexto dos templates
+type templatePeer struct {
+	Name     string
+	Distro   string
+	IPv4     string
+	Groups   []string
+	Networks []Network
+}
+
+// templateNIC é uma interface da VM no contexto dos templates, com o nome a usar no network-config
+type templateNIC struct {
+	Network
+	Name string
+	// Primary indica a primeira interface, a única com a rota por omissão (gateway4)
+	Primary bool
+}
+
+// templateContext é o contexto comum a todos os templates cloud-init de uma VM.
+// Os campos de nível superior mantêm os nomes usados pelos templates existentes.
+type templateContext struct {
+	// user-data
+	Username          string
+	SSHPublicKey      string
+	SSHAuthorizedKeys []string
+	PasswordHash      string
+	LockPasswd        bool
+	SSHPwauth         bool
+
+	// network-config (primeira interface)
+	NICName          string
+	MACAddress       string
+	GuestIPv4        string
+	GuestPrefix      int
+	GuestGateway4    string
+	GuestNameservers []string
+
+	// meta-data
+	InstanceID string
+	Hostname   string
+
+	// Contexto completo
+	VM       *VM
+	Distro   *DistroInfo
+	Networks []Network
+	// Interfaces são as interfaces da VM com o nome para o network-config
+	Interfaces []templateNIC
+	Groups     []string
+	Peers      []templatePeer
+	Project    string
+	Vars       map[string]interface{}
+}
+
+// templateContext constrói o contexto dos templates da VM, com as redes já resolvidas.
+// As credenciais (chaves SSH e password) são preenchidas por renderCloudInit.
+func (kvm *KVMCompose) templateContext(vm *VM, distroInfo *DistroInfo) *templateContext {
+	_, _, defaultGateway, defaultNameservers := kvm.getDefaultValues()
+
+	networks := make([]Network, len(vm.Networks))
+	for i, network := range vm.Networks {
+		if network.HostBridge == "" {
+			network.HostBridge = "br0"
+		}
+		if network.GuestGateway4 == "" {
+			network.GuestGateway4 = defaultGateway
+		}
+		if len(network.GuestNameservers) == 0 {
+			network.GuestNameservers = defaultNameservers
+		}
+		if network.GuestPrefix == 0 {
+			network.GuestPrefix = 24
+		}
+		networks[i] = network
+	}
+	interfaces := make([]templateNIC, len(networks))
+	for i, network := range networks {
+		interfaces[i] = templateNIC{Network: network, Name: distroInfo.nicNameAt(i), Primary: i == 0}
+	}
+
+	ctx := &templateContext{
+		Username:   vm.Username,
+		NICName:    distroInfo.nicName(),
+		InstanceID: vm.Name,
+		Hostname:   vm.Name,
+		VM:         vm,
+		Distro:     distroInfo,
+		Networks:   networks,
+		Interfaces: interfaces,
+		Groups:     vm.Group,
+		Project:    kvm.projectName(),
+		Vars:       kvm.config.Vars,
+	}
+	if len(networks) > 0 {
+		ctx.MACAddress = networks[0].MAC
+		ctx.GuestIPv4 = networks[0].GuestIPv4
+		ctx.GuestPrefix = networks[0].GuestPrefix
+		ctx.GuestGateway4 = networks[0].GuestGateway4
+		ctx.GuestNameservers = networks[0].GuestNameservers
+	}
+	if ctx.Vars == nil {
+		ctx.Vars = map[string]interface{}{}
+	}
+
+	for _, peer := range kvm.config.VMs {
+		if peer.Name == vm.Name {
+			continue
+		}
+		p := templatePeer{Name: peer.Name, Distro: peer.Distro, Groups: peer.Group, Networks: peer.Networks}
+		if len(peer.Networks) > 0 {
+			p.IPv4 = peer.Networks[0].GuestIPv4
+		}
+		ctx.Peers = append(ctx.Peers, p)
+	}
+	return ctx
+}
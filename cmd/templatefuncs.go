@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs retorna as funções auxiliares disponíveis nos templates, inspiradas no sprig
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Texto
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       templateJoin,
+		"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"squote":     func(v interface{}) string { return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'" },
+		"indent":     templateIndent,
+		"nindent":    func(spaces int, s string) string { return "\n" + templateIndent(spaces, s) },
+
+		// Valores
+		"default": templateDefault,
+		"empty":   templateEmpty,
+		"ternary": func(yes, no interface{}, cond bool) interface{} {
+			if cond {
+				return yes
+			}
+			return no
+		},
+
+		// Listas e mapas
+		"list":    func(items ...interface{}) []interface{} { return items },
+		"dict":    templateDict,
+		"has":     templateHas,
+		"inGroup": func(group string, groups []string) bool { return templateHas(group, groups) },
+		"first":   func(v interface{}) interface{} { return templateIndex(v, 0) },
+		"last":    func(v interface{}) interface{} { return templateIndex(v, -1) },
+
+		// Codificação
+		"toYaml":    templateToYAML,
+		"toJson":    templateToJSON,
+		"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":    templateB64Dec,
+		"sha256sum": func(s string) string { sum := sha256.Sum256([]byte(s)); return hex.EncodeToString(sum[:]) },
+
+		// Ambiente e ficheiros do host
+		"env":      os.Getenv,
+		"readFile": templateReadFile,
+	}
+}
+
+// templateJoin junta os elementos de uma lista com o separador indicado
+func templateJoin(sep string, list interface{}) string {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+	items := make([]string, value.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
+
+// templateIndent indenta todas as linhas de s com o número de espaços indicado
+func templateIndent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// templateEmpty indica se um valor é nulo ou o valor zero do seu tipo (incluindo listas e mapas vazios)
+func templateEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return value.IsZero()
+}
+
+// templateDefault retorna value, ou fallback se value estiver vazio
+func templateDefault(fallback, value interface{}) interface{} {
+	if templateEmpty(value) {
+		return fallback
+	}
+	return value
+}
+
+// templateDict cria um mapa a partir de pares chave/valor
+func templateDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requer um número par de argumentos")
+	}
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		dict[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return dict, nil
+}
+
+// templateHas indica se a lista contém o elemento
+func templateHas(needle interface{}, list interface{}) bool {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < value.Len(); i++ {
+		if reflect.DeepEqual(value.Index(i).Interface(), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateIndex retorna o elemento i de uma lista (negativo conta a partir do fim), ou nil
+func templateIndex(list interface{}, i int) interface{} {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array || value.Len() == 0 {
+		return nil
+	}
+	if i < 0 {
+		i += value.Len()
+	}
+	return value.Index(i).Interface()
+}
+
+// templateToYAML codifica um valor em YAML, sem a quebra de linha final
+func templateToYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	return strings.TrimSuffix(string(data), "\n"), err
+}
+
+// templateToJSON codifica um valor em JSON
+func templateToJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// templateB64Dec descodifica um texto em base64
+func templateB64Dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	return string(data), err
+}
+
+// templateReadFile lê um ficheiro do host (~ é expandido)
+func templateReadFile(path string) (string, error) {
+	data, err := os.ReadFile(expandPath(path))
+	return string(data), err
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/paulozagaloneves/kvm-compose/templates"
+	"github.com/spf13/cobra"
+)
+
+// listTemplates retorna os nomes dos templates cloud-init embutidos e dos encontrados nos diretórios de templates
+func listTemplates() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(filename string) {
+		name := filepath.Base(filename)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	embedded, _ := fs.Glob(templates.FS, "*.tmpl")
+	for _, match := range embedded {
+		add(match)
+	}
+	for _, dir := range templateDirs() {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TemplatesList mostra, para cada template cloud-init, a origem usada (local, utilizador ou embutido).
+// Com nomes de VMs, mostra o template escolhido para cada uma.
+func (kvm *KVMCompose) TemplatesList(names []string) error {
+	if len(names) > 0 {
+		return kvm.templatesListVMs(names)
+	}
+	color.Cyan("=== Templates cloud-init ===")
+	color.New(color.FgGreen, color.Bold).Printf("%-32s %s\n", "Template", "Origem")
+	color.New(color.FgGreen, color.Bold).Printf("%-32s %s\n", strings.Repeat("-", 32), strings.Repeat("-", 40))
+	for _, name := range listTemplates() {
+		_, origin, err := readTemplateFile(name)
+		if err != nil {
+			origin = "-"
+		}
+		fmt.Printf("%-32s %s\n", name, origin)
+	}
+	return nil
+}
+
+// templatesListVMs mostra os templates cloud-init resolvidos para as VMs indicadas
+func (kvm *KVMCompose) templatesListVMs(names []string) error {
+	if err := kvm.loadConfig(); err != nil {
+		return err
+	}
+	vms, err := kvm.selectVMs(names)
+	if err != nil {
+		return err
+	}
+	color.Cyan("=== Templates cloud-init por VM ===")
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-16s %s\n", "VM", "Template", "Origem")
+	color.New(color.FgGreen, color.Bold).Printf("%-15s %-16s %s\n", strings.Repeat("-", 15), strings.Repeat("-", 16), strings.Repeat("-", 40))
+	for _, vm := range vms {
+		distroInfo, err := vmDistroInfo(&vm)
+		if err != nil {
+			distroInfo = &DistroInfo{}
+		}
+		for _, name := range cloudInitTemplates {
+			_, origin, err := resolveTemplate(&vm, distroInfo, name)
+			if err != nil {
+				origin = err.Error()
+			}
+			fmt.Printf("%-15s %-16s %s\n", vm.Name, name, origin)
+		}
+	}
+	return nil
+}
+
+// TemplatesInit escreve os templates cloud-init embutidos em ./templates (ou na pasta do
+// utilizador com global) para serem personalizados. Os existentes só são substituídos com force.
+func (kvm *KVMCompose) TemplatesInit(global, force bool) error {
+	dir := localTemplateDir
+	if global {
+		var err error
+		if dir, err = userTemplateDir(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
+
+	names, _ := fs.Glob(templates.FS, "*.tmpl")
+	writtenCount := 0
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !force {
+			color.Yellow("⚠️  %s já existe, mantido (use --force para substituir)", path)
+			continue
+		}
+		data, err := fs.ReadFile(templates.FS, name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("erro ao escrever %s: %v", path, err)
+		}
+		color.Green("📝 %s", path)
+		writtenCount++
+	}
+	fmt.Printf("Templates escritos: %d\n", writtenCount)
+	return nil
+}
+
+var (
+	templatesInitGlobal bool
+	templatesInitForce  bool
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Gerir os templates cloud-init",
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:     "ls [vm...]",
+	Aliases: []string{"list"},
+	Short:   "Listar os templates cloud-init e a origem de cada um (ou os escolhidos para as VMs indicadas)",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.TemplatesList(args); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var templatesInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Escrever os templates cloud-init padrão em ./templates para personalização",
+	Run: func(cmd *cobra.Command, args []string) {
+		kvm := NewKVMCompose(composeFile)
+		if err := kvm.TemplatesInit(templatesInitGlobal, templatesInitForce); err != nil {
+			color.Red("Erro: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Register templates command
+	templatesInitCmd.Flags().BoolVar(&templatesInitGlobal, "global", false, "Escrever em ~/.config/kvm-compose/templates em vez de ./templates")
+	templatesInitCmd.Flags().BoolVar(&templatesInitForce, "force", false, "Substituir templates existentes")
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesInitCmd)
+	rootCmd.AddCommand(templatesCmd)
+}
@@ -3,34 +3,72 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// UpOptions representa as opções do comando up
+type UpOptions struct {
+	IgnoreIPConflicts bool
+	ARPProbe          bool
+	// Wait ativa o phone_home do cloud-init e aguarda que as VMs criadas o reportem
+	Wait          bool
+	WaitTimeout   time.Duration
+	PhoneHomePort int
+}
+
 // Up cria e inicia todas as VMs
-func (kvm *KVMCompose) Up() error {
+func (kvm *KVMCompose) Up(opts UpOptions) error {
 	err := kvm.loadConfig()
 	if err != nil {
 		return err
 	}
 
+	// Alocar IPs para as interfaces sem guest_ipv4
+	if err := kvm.resolveNetworks(true, true); err != nil {
+		return err
+	}
+
+	// Verificar conflitos de IP antes de criar qualquer VM
+	color.Cyan("=== Verificando conflitos de IP ===")
+	conflicts := kvm.checkIPConflicts(opts.ARPProbe)
+	if len(conflicts) == 0 {
+		color.Green("✅ Nenhum conflito de IP encontrado")
+	} else {
+		printIPConflicts(conflicts)
+		if opts.IgnoreIPConflicts {
+			color.Yellow("⚠️  Conflitos ignorados (--ignore-ip-conflicts)")
+		}
+	}
+	fmt.Println()
+
+	// Servidor phone_home: as VMs criadas reportam quando o cloud-init termina
+	if opts.Wait {
+		kvm.phoneHome = newPhoneHomeServer(kvm, opts.PhoneHomePort)
+		defer kvm.phoneHome.close()
+	}
+
 	color.Cyan("=== Criando todas as VMs do compose ===")
 
-	// Baixar cada imagem base de distro apenas uma vez
-	downloadedDistros := make(map[string]bool)
+	// Baixar cada imagem base apenas uma vez
+	downloadedImages := make(map[string]bool)
 
 	createdCount := 0
 	skippedCount := 0
+	conflictCount := 0
+	var created []string
 
 	for _, vm := range kvm.config.VMs {
-		// Baixar imagem base da distro da VM apenas se ainda não foi baixada nesta execução
-		if !downloadedDistros[vm.Distro] {
+		// Baixar imagem base da VM apenas se ainda não foi baixada nesta execução
+		baseImagePath := kvm.getBaseImagePath(&vm)
+		if !downloadedImages[baseImagePath] {
 			if err := kvm.downloadBaseImage(&vm); err != nil {
 				color.Red("❌ Erro ao baixar imagem base para %s: %v", vm.Name, err)
 				continue
 			}
-			downloadedDistros[vm.Distro] = true
+			downloadedImages[baseImagePath] = true
 		}
 		color.White("--- Processando VM: %s ---", vm.Name)
 
@@ -42,97 +80,174 @@ func (kvm *KVMCompose) Up() error {
 			continue
 		}
 
+		if len(vm.Networks) == 0 || !hasAllIPs(&vm) {
+			color.Red("❌ VM %s sem guest_ipv4 e sem subnet para alocação automática", vm.Name)
+			fmt.Println()
+			continue
+		}
+
+		if len(conflicts[vm.Name]) > 0 && !opts.IgnoreIPConflicts {
+			color.Red("❌ VM %s não será criada por conflito de IP (use --ignore-ip-conflicts para forçar)", vm.Name)
+			conflictCount++
+			fmt.Println()
+			continue
+		}
+
+		kvm.applyVMDefaults(&vm)
+
 		// Mostrar configurações
 		color.Blue("🛠️ Configurações:")
-		fmt.Printf("  Distro: %s\n", vm.Distro)
+		if vm.Image != "" {
+			fmt.Printf("  Imagem: %s\n", vm.Image)
+		} else {
+			fmt.Printf("  Distro: %s\n", vm.Distro)
+		}
 		fmt.Printf("  Usuário: %s\n", vm.Username)
 		fmt.Printf("  IP: %s\n", vm.Networks[0].GuestIPv4)
 		fmt.Printf("  Memória: %dMB\n", vm.Memory)
 		fmt.Printf("  vCPUs: %d\n", vm.VCPUs)
-		fmt.Printf("  Disco: %dGB\n", vm.DiskSize)
+		fmt.Printf("  Disco: %dGB (%s)\n", vm.DiskSize, vm.DiskMode)
 		fmt.Printf("  Bridge: %s\n", vm.Networks[0].HostBridge)
+		fmt.Printf("  MAC: %s\n", vm.Networks[0].MAC)
 
-		// Copiar imagem base para imagem da VM
-		baseImagePath := kvm.getBaseImagePath(&vm)
+		// Criar disco da VM a partir da imagem base (o nome pode ter mudado com um refresh)
+		baseImagePath = kvm.getBaseImagePath(&vm)
 		vmImagePath := kvm.getVMImagePath(vm.Name)
-
-		color.Cyan("📋 Copiando: %s → %s", baseImagePath, vmImagePath)
-		err := execCommand("cp", baseImagePath, vmImagePath)
-		if err != nil {
-			color.Red("❌ Erro ao copiar imagem para %s: %v", vm.Name, err)
+		// A VM não existe: um disco com o nome dela é de uma tentativa anterior falhada
+		if kvm.removeDisk(vmImagePath) {
+			color.Yellow("🧹 Disco %s de uma tentativa anterior removido", vmImagePath)
+		}
+		// Em caso de falha, remover o que foi criado para a VM para que o próximo up recomece do zero
+		var newDisks []string
+		cleanup := func() {
+			for _, path := range append([]string{vmImagePath, kvm.getSeedPath(vm.Name)}, newDisks...) {
+				if kvm.removeDisk(path) {
+					color.Yellow("🧹 %s removido", path)
+				}
+			}
+		}
+		if err := kvm.createVMDisk(&vm, baseImagePath, vmImagePath); err != nil {
+			color.Red("❌ Erro ao criar disco de %s: %v", vm.Name, err)
+			cleanup()
 			continue
 		}
 
-		// Ajustar permissões
-		execCommand("sudo", "chmod", "644", vmImagePath)
-
-		// Redimensionar imagem para o tamanho configurado
-		color.Cyan("🔧 Redimensionando imagem %s para %dG...", vmImagePath, vm.DiskSize)
-		if err := execCommand("qemu-img", "resize", vmImagePath, fmt.Sprintf("%dG", vm.DiskSize)); err != nil {
-			color.Red("❌ Erro ao redimensionar imagem %s: %v", vm.Name, err)
+		// Criar discos de dados e volumes
+		disks, err := kvm.resolveDataDisks(&vm)
+		if err == nil {
+			newDisks, err = kvm.createDataDisks(disks)
+		}
+		if err != nil {
+			color.Red("❌ Erro ao criar discos de dados para %s: %v", vm.Name, err)
+			cleanup()
 			continue
 		}
+		for _, d := range disks {
+			color.Cyan("💽 Disco de dados %s: %s (%dG)", d.Name, d.Path, d.Size)
+		}
 
-		// Criar arquivos cloud-init
-		err = kvm.createCloudInitFiles(&vm)
+		// Criar seed cloud-init (NoCloud)
+		seedPath, err := kvm.createSeedISO(&vm)
 		if err != nil {
-			color.Red("❌ Erro ao criar arquivos cloud-init para %s: %v", vm.Name, err)
+			color.Red("❌ Erro ao criar seed cloud-init para %s: %v", vm.Name, err)
+			cleanup()
 			continue
 		}
 
 		// Executar virt-install
 		color.Cyan("🚀 Criando VM %s...", vm.Name)
-		// Carregar OSVARIANT do INI da distro
-		distroInfo, err := loadDistroInfo(vm.Distro)
-		osVariant := ""
-		if err == nil {
-			osVariant = distroInfo.OSVariant
-		} else {
-			osVariant = "generic"
+		// Carregar OSVARIANT e particularidades do INI da distro (ou do os_variant da VM)
+		distroInfo, err := vmDistroInfo(&vm)
+		if err != nil {
+			distroInfo = &DistroInfo{OSVariant: "generic"}
 		}
 		args := []string{
 			"--name", vm.Name,
 			"--memory", fmt.Sprintf("%d", vm.Memory),
 			"--vcpus", fmt.Sprintf("%d", vm.VCPUs),
-			"--os-variant", osVariant,
+			"--os-variant", distroInfo.OSVariant,
 			"--virt-type", "kvm",
-			"--disk", fmt.Sprintf("%s,size=%d,format=qcow2", vmImagePath, vm.DiskSize),
-			"--network", fmt.Sprintf("bridge=%s,model=virtio", vm.Networks[0].HostBridge),
+			"--disk", fmt.Sprintf("%s,size=%d,format=qcow2", kvm.diskSource(vmImagePath), vm.DiskSize),
+		}
+		for _, d := range disks {
+			args = append(args, "--disk", fmt.Sprintf("%s,format=%s,bus=%s,serial=%s", kvm.diskSource(d.Path), d.Format, d.Bus, d.Serial))
+		}
+		args = append(args, "--disk", fmt.Sprintf("%s,device=cdrom", kvm.diskSource(seedPath)))
+		// Consola série registada no diretório de trabalho da VM (kvm-compose logs)
+		if logPath, err := kvm.prepareConsoleLog(vm.Name); err == nil {
+			args = append(args, "--serial", fmt.Sprintf("pty,log.file=%s,log.append=on", logPath))
+		} else {
+			color.Yellow("⚠️  Consola série de %s sem log: %v", vm.Name, err)
+		}
+		// Uma interface por rede, com MAC estável
+		for _, nic := range vm.Networks {
+			bridge := nic.HostBridge
+			if bridge == "" {
+				bridge = "br0"
+			}
+			args = append(args, "--network", fmt.Sprintf("bridge=%s,model=virtio,mac=%s", bridge, nic.MAC))
+		}
+		args = append(args,
 			"--graphics", "spice,listen=0.0.0.0",
 			"--noautoconsole",
 			"--import",
-			"--cloud-init",
-			fmt.Sprintf("user-data=%s-user-data.yaml,network-config=%s-network-config.yaml,meta-data=%s-meta-data.yaml",
-				vm.Name, vm.Name, vm.Name),
-		}
+		)
+		args = append(args, distroInfo.installArgs()...)
 
 		if err := execCommand("virt-install", args...); err != nil {
 			color.Red("❌ Falha ao criar VM %s: %v", vm.Name, err)
+			// Se o domínio chegou a ser definido, os discos ficam para o down
+			if !vmExists(vm.Name) {
+				cleanup()
+			}
 		} else {
 			color.Green("✅ VM %s criada com sucesso!", vm.Name)
 			color.Cyan("   SSH: ssh %s@%s", vm.Username, vm.Networks[0].GuestIPv4)
 			createdCount++
+			created = append(created, vm.Name)
 		}
-
-		// Limpar arquivos temporários
-		cleanupCloudInitFiles(vm.Name)
 		fmt.Println()
 	}
 
 	color.Cyan("=== Resumo ===")
 	fmt.Printf("VMs criadas: %d\n", createdCount)
 	fmt.Printf("VMs puladas (já existem): %d\n", skippedCount)
+	if conflictCount > 0 {
+		fmt.Printf("VMs não criadas (conflito de IP): %d\n", conflictCount)
+	}
 	fmt.Printf("Total de VMs no compose: %d\n", len(kvm.config.VMs))
 
+	if opts.Wait && len(created) > 0 {
+		fmt.Println()
+		color.Cyan("=== Aguardando cloud-init (timeout %s) ===", opts.WaitTimeout)
+		reports := kvm.phoneHome.wait(created, opts.WaitTimeout)
+		printPhoneHomeSummary(created, reports)
+		if missing := len(created) - len(reports); missing > 0 {
+			return fmt.Errorf("%d VM(s) não reportaram o fim do cloud-init em %s", missing, opts.WaitTimeout)
+		}
+	}
+
 	return nil
 }
 
+// hasAllIPs verifica se todas as interfaces da VM têm IP definido ou alocado
+func hasAllIPs(vm *VM) bool {
+	for _, nic := range vm.Networks {
+		if nic.GuestIPv4 == "" {
+			return false
+		}
+	}
+	return true
+}
+
+var upOptions UpOptions
+
 var upCmd = &cobra.Command{
 	Use:   "up",
 	Short: "Criar e iniciar todas as VMs do compose",
 	Run: func(cmd *cobra.Command, args []string) {
 		kvm := NewKVMCompose(composeFile)
-		if err := kvm.Up(); err != nil {
+		if err := kvm.Up(upOptions); err != nil {
 			color.Red("Erro: %v", err)
 			os.Exit(1)
 		}
@@ -145,5 +260,10 @@ var upCmd = &cobra.Command{
 }
 
 func init() {
+	upCmd.Flags().BoolVar(&upOptions.IgnoreIPConflicts, "ignore-ip-conflicts", false, "Criar VMs mesmo com conflitos de IP")
+	upCmd.Flags().BoolVar(&upOptions.ARPProbe, "arp-probe", false, "Verificar via ARP (arping) se os IPs já estão em uso na bridge")
+	upCmd.Flags().BoolVar(&upOptions.Wait, "wait", false, "Aguardar que o cloud-init das VMs criadas termine (phone_home)")
+	upCmd.Flags().DurationVar(&upOptions.WaitTimeout, "wait-timeout", 10*time.Minute, "Tempo máximo de espera com --wait")
+	upCmd.Flags().IntVar(&upOptions.PhoneHomePort, "phone-home-port", 0, "Porta do listener phone_home no host (0 = porta livre)")
 	rootCmd.AddCommand(upCmd)
 }
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -46,52 +50,268 @@ func (kvm *KVMCompose) getVMByName(name string) (*VM, error) {
 	return nil, fmt.Errorf("VM '%s' não encontrada", name)
 }
 
+// selectVMs retorna as VMs do compose com os nomes indicados, ou todas se nenhum nome for dado
+func (kvm *KVMCompose) selectVMs(names []string) ([]VM, error) {
+	if len(names) == 0 {
+		return kvm.config.VMs, nil
+	}
+	var vms []VM
+	for _, name := range names {
+		vm, err := kvm.getVMByName(name)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, *vm)
+	}
+	return vms, nil
+}
+
 // vmExists verifica se uma VM existe no libvirt
 func vmExists(name string) bool {
 	_, err := execCommandOutput("virsh", "dominfo", name)
 	return err == nil
 }
 
-// downloadBaseImage baixa a imagem base da distro da VM se não existir
+// vmDistroInfo retorna as informações da imagem base da VM: a imagem própria (image),
+// um caminho local ou URL, ou a da distro. O os_variant da VM tem prioridade sobre o da distro.
+func vmDistroInfo(vm *VM) (*DistroInfo, error) {
+	if vm.Image == "" {
+		distroInfo, err := loadDistroInfo(vm.Distro)
+		if err != nil {
+			return nil, err
+		}
+		if vm.OSVariant != "" {
+			distroInfo.OSVariant = vm.OSVariant
+		}
+		return distroInfo, nil
+	}
+
+	// Caminhos locais passam pelo cache como URLs file://
+	imageURL := vm.Image
+	if !strings.Contains(imageURL, "://") {
+		absPath, err := filepath.Abs(expandPath(imageURL))
+		if err != nil {
+			return nil, err
+		}
+		imageURL = (&url.URL{Scheme: "file", Path: absPath}).String()
+	}
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("image inválido '%s': %v", vm.Image, err)
+	}
+	base := path.Base(parsed.Path)
+	if base == "/" || base == "." {
+		return nil, fmt.Errorf("image inválido '%s': sem nome de ficheiro", vm.Image)
+	}
+	// O nome em cache inclui um hash do URL completo: imagens diferentes com o mesmo
+	// nome de ficheiro (ex.: .../v1/disk.qcow2 e .../v2/disk.qcow2) não partilham o cache
+	sum := sha256.Sum256([]byte(imageURL))
+	ext := path.Ext(base)
+	source := fmt.Sprintf("%s-%x%s", strings.TrimSuffix(base, ext), sum[:6], ext)
+
+	// Com distro, as particularidades (consola, firmware, rede...) vêm do INI dela
+	distroInfo := &DistroInfo{}
+	if vm.Distro != "" {
+		if distroInfo, err = loadDistroInfo(vm.Distro); err != nil {
+			return nil, err
+		}
+	}
+	distroInfo.URL = imageURL
+	distroInfo.Source = source
+	distroInfo.SHA256 = vm.ImageSHA256
+	distroInfo.ChecksumURL = ""
+	distroInfo.Mirrors = nil
+	distroInfo.MaxAge = 0
+	if vm.OSVariant != "" {
+		distroInfo.OSVariant = vm.OSVariant
+	}
+	if distroInfo.OSVariant == "" {
+		distroInfo.OSVariant = "generic"
+	}
+	return distroInfo, nil
+}
+
+// vmImageName retorna o nome da imagem base da VM para mensagens: a distro ou o ficheiro do image
+func vmImageName(vm *VM) string {
+	if vm.Image != "" {
+		return filepath.Base(vm.Image)
+	}
+	return vm.Distro
+}
+
+// downloadBaseImage baixa a imagem base da VM se não existir
 func (kvm *KVMCompose) downloadBaseImage(vm *VM) error {
+	distroInfo, err := vmDistroInfo(vm)
+	if err != nil {
+		return fmt.Errorf("erro ao obter informações da imagem de '%s': %v", vm.Name, err)
+	}
+	return kvm.downloadImage(vmImageName(vm), distroInfo, false)
+}
+
+// downloadDistroImage baixa a imagem base de uma distro se não existir. Com refresh,
+// ou quando o MAX_AGE da distro expirou, verifica também se há uma versão nova.
+func (kvm *KVMCompose) downloadDistroImage(distro string, refresh bool) error {
 	// Carregar informações da distro
-	distroInfo, err := loadDistroInfo(vm.Distro)
+	distroInfo, err := loadDistroInfo(distro)
 	if err != nil {
-		return fmt.Errorf("erro ao obter informações da distro '%s': %v", vm.Distro, err)
+		return fmt.Errorf("erro ao obter informações da distro '%s': %v", distro, err)
 	}
+	return kvm.downloadImage(distro, distroInfo, refresh)
+}
 
+// downloadImage baixa para o cache a imagem descrita por distroInfo, verificando-a e
+// atualizando-a como descrito em downloadDistroImage
+func (kvm *KVMCompose) downloadImage(distro string, distroInfo *DistroInfo, refresh bool) error {
 	// Criar diretórios se não existirem
 	upstreamDir := expandPath(kvm.appConfig.Images.PathUpstreamImages)
-	err = os.MkdirAll(upstreamDir, 0755)
+	err := os.MkdirAll(upstreamDir, 0755)
 	if err != nil {
 		color.Yellow("⚠️  Erro ao criar diretório %s: %v", upstreamDir, err)
 		upstreamDir = "." // Fallback para diretório atual
 	}
 
-	imageName := distroInfo.Source
-	imagePath := filepath.Join(upstreamDir, imageName)
-	url := distroInfo.URL
+	meta := loadImageMeta(upstreamDir, distroInfo)
+	imagePath := kvm.imagePath(distroInfo)
+
 	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		color.Cyan("📥 Baixando imagem base da distro %s...", vm.Distro)
-		color.Cyan("📂 Salvando em: %s", imagePath)
-		if err := execCommand("wget", "-O", imagePath, url); err != nil {
-			return fmt.Errorf("erro ao baixar imagem: %v", err)
-		}
-	} else {
+		return kvm.fetchDistroImage(distro, distroInfo, meta, filepath.Join(upstreamDir, distroInfo.Source))
+	}
+
+	if refresh || meta.expired(distroInfo.MaxAge) {
+		return kvm.refreshDistroImage(distro, distroInfo, meta, imagePath)
+	}
+	return verifyCachedImage(distro, distroInfo, meta, imagePath)
+}
+
+// verifyCachedImage verifica a imagem em cache antes de usá-la, pelo checksum
+// registado no download. Imagens sem checksum registado são adotadas (adoptCachedImage).
+func verifyCachedImage(distro string, distroInfo *DistroInfo, meta *imageMeta, imagePath string) error {
+	expected := meta.Checksum
+	if expected == "" {
+		return adoptCachedImage(distro, distroInfo, meta, imagePath)
+	}
+	if meta.alreadyVerified(imagePath) {
 		color.Green("✅ Imagem base já existe: %s", imagePath)
+		return nil
 	}
+	if err := verifyImage(imagePath, expected); err != nil {
+		return fmt.Errorf("%v (atualize-a com 'kvm-compose images pull --refresh %s' ou remova-a com 'kvm-compose images rm %s')", err, distro, distro)
+	}
+	meta.markVerified(imagePath)
+	if err := meta.save(); err != nil {
+		color.Yellow("⚠️  Erro ao gravar metadados de %s: %v", meta.File, err)
+	}
+	color.Green("✅ Imagem base já existe: %s", imagePath)
 	return nil
 }
 
-// getBaseImagePath retorna o caminho da imagem base da distro da VM
+// adoptCachedImage regista o checksum de uma imagem em cache baixada antes do registo de
+// checksums. O checksum atual da distro é verificado, mas numa imagem "latest" provavelmente
+// já mudou: a diferença é apenas um aviso e fica registado o checksum do próprio ficheiro.
+func adoptCachedImage(distro string, distroInfo *DistroInfo, meta *imageMeta, imagePath string) error {
+	expected, err := expectedChecksum(distroInfo)
+	if err != nil {
+		color.Yellow("⚠️  Não foi possível obter o checksum da distro %s: %v", distro, err)
+	}
+	if expected != "" {
+		if err := verifyImage(imagePath, expected); err != nil {
+			color.Yellow("⚠️  %v", err)
+			color.Yellow("⚠️  A imagem em cache é provavelmente de uma versão anterior (atualize-a com 'kvm-compose images pull --refresh %s')", distro)
+			expected = ""
+		}
+	}
+	if expected == "" {
+		if expected, err = sha256File(imagePath); err != nil {
+			return err
+		}
+	}
+	meta.markVerified(imagePath)
+
+	meta.File = filepath.Base(imagePath)
+	if meta.URL == "" {
+		meta.URL = distroInfo.URL
+	}
+	meta.Checksum = expected
+	if meta.DownloadedAt.IsZero() {
+		if info, err := os.Stat(imagePath); err == nil {
+			meta.DownloadedAt = info.ModTime()
+		}
+	}
+	if err := meta.save(); err != nil {
+		color.Yellow("⚠️  Erro ao gravar metadados de %s: %v", meta.File, err)
+	}
+	color.Green("✅ Imagem base já existe: %s", imagePath)
+	return nil
+}
+
+// fetchDistroImage baixa a imagem da distro para imagePath e regista os metadados do download
+func (kvm *KVMCompose) fetchDistroImage(distro string, distroInfo *DistroInfo, meta *imageMeta, imagePath string) error {
+	expected, err := expectedChecksum(distroInfo)
+	if err != nil {
+		color.Yellow("⚠️  Não foi possível obter o checksum da distro %s: %v", distro, err)
+	}
+
+	// Baixar para um ficheiro temporário e renomear apenas após a verificação
+	partPath := imagePath + partialSuffix
+	color.Cyan("📥 Baixando imagem base da distro %s...", distro)
+	color.Cyan("📂 Salvando em: %s", imagePath)
+	// Um .part de uma tentativa anterior é retomado; os mirrors são tentados por ordem
+	version, err := downloadFile(append([]string{distroInfo.URL}, distroInfo.Mirrors...), partPath)
+	if err != nil {
+		return fmt.Errorf("erro ao baixar imagem: %v", err)
+	}
+	if err := verifyImage(partPath, expected); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	if expected == "" {
+		// Sem checksum da distro, registar o do ficheiro baixado para verificações futuras
+		if expected, err = sha256File(partPath); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(partPath, imagePath); err != nil {
+		return fmt.Errorf("erro ao mover imagem para %s: %v", imagePath, err)
+	}
+
+	now := time.Now()
+	meta.File = filepath.Base(imagePath)
+	meta.URL = distroInfo.URL
+	meta.SourceURL = version.URL
+	meta.ETag = version.ETag
+	meta.LastModified = version.LastModified
+	meta.Checksum = expected
+	meta.DownloadedAt = now
+	meta.CheckedAt = now
+	meta.markVerified(imagePath)
+	return meta.save()
+}
+
+// getBaseImagePath retorna o caminho da imagem base da VM (da distro ou do image)
 func (kvm *KVMCompose) getBaseImagePath(vm *VM) string {
+	distroInfo, err := vmDistroInfo(vm)
+	if err != nil {
+		return filepath.Join(expandPath(kvm.appConfig.Images.PathUpstreamImages), "base.qcow2") // fallback
+	}
+	return kvm.imagePath(distroInfo)
+}
+
+// getDistroImagePath retorna o caminho da imagem base atual de uma distro
+func (kvm *KVMCompose) getDistroImagePath(distro string) string {
+	distroInfo, err := loadDistroInfo(distro)
+	if err != nil {
+		return filepath.Join(expandPath(kvm.appConfig.Images.PathUpstreamImages), "base.qcow2") // fallback
+	}
+	return kvm.imagePath(distroInfo)
+}
+
+// imagePath retorna o caminho da imagem em cache descrita por distroInfo
+func (kvm *KVMCompose) imagePath(distroInfo *DistroInfo) string {
 	upstreamDir := expandPath(kvm.appConfig.Images.PathUpstreamImages)
-	distroInfo, err := loadDistroInfo(vm.Distro)
-	imageName := ""
-	if err == nil {
-		imageName = distroInfo.Source
-	} else {
-		imageName = "base.qcow2" // fallback
+	imageName := distroInfo.Source
+	// Após um refresh a imagem atual pode ter outro nome
+	if meta := loadImageMeta(upstreamDir, distroInfo); meta.File != "" {
+		imageName = meta.File
 	}
 	return filepath.Join(upstreamDir, imageName)
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVMDistroInfoImageCacheName(t *testing.T) {
+	v1, err := vmDistroInfo(&VM{Name: "a", Image: "https://example.com/v1/disk.qcow2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := vmDistroInfo(&VM{Name: "b", Image: "https://example.com/v2/disk.qcow2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Source == v2.Source {
+		t.Errorf("URLs diferentes com o mesmo nome partilham o cache: %s", v1.Source)
+	}
+	for _, info := range []*DistroInfo{v1, v2} {
+		if !strings.HasPrefix(info.Source, "disk-") || !strings.HasSuffix(info.Source, ".qcow2") {
+			t.Errorf("nome em cache inesperado: %s", info.Source)
+		}
+	}
+
+	again, err := vmDistroInfo(&VM{Name: "c", Image: "https://example.com/v1/disk.qcow2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Source != v1.Source {
+		t.Errorf("o mesmo URL deu nomes diferentes: %s e %s", again.Source, v1.Source)
+	}
+}
+
+func TestVerifyCachedImageAdoptsLegacyCache(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "debian.qcow2")
+	if err := os.WriteFile(imagePath, []byte("imagem antiga"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// O checksum da distro já mudou para uma versão nova
+	distroInfo := &DistroInfo{URL: "https://example.com/debian.qcow2", Source: "debian.qcow2", SHA256: strings.Repeat("a", 64)}
+	meta := loadImageMeta(dir, distroInfo)
+
+	if err := verifyCachedImage("debian", distroInfo, meta, imagePath); err != nil {
+		t.Fatalf("imagem em cache sem checksum registado bloqueou o uso: %v", err)
+	}
+	own, _ := sha256File(imagePath)
+	saved := loadImageMeta(dir, distroInfo)
+	if saved.Checksum != own {
+		t.Errorf("checksum registado %q, esperado o do ficheiro %q", saved.Checksum, own)
+	}
+
+	// A partir daqui, uma alteração ao ficheiro é detetada
+	os.WriteFile(imagePath, []byte("imagem corrompida"), 0644)
+	if err := verifyCachedImage("debian", distroInfo, saved, imagePath); err == nil {
+		t.Error("imagem alterada depois de registada passou na verificação")
+	}
+}
+
+func TestVerifyCachedImageSkipsUnchangedFile(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "debian.qcow2")
+	os.WriteFile(imagePath, []byte("imagem boa"), 0644)
+	sum, _ := sha256File(imagePath)
+	distroInfo := &DistroInfo{URL: "https://example.com/debian.qcow2", Source: "debian.qcow2"}
+	meta := loadImageMeta(dir, distroInfo)
+	meta.Checksum = sum
+
+	if err := verifyCachedImage("debian", distroInfo, meta, imagePath); err != nil {
+		t.Fatal(err)
+	}
+	saved := loadImageMeta(dir, distroInfo)
+	if !saved.alreadyVerified(imagePath) {
+		t.Fatalf("verificação não registada: %+v", saved)
+	}
+
+	// Mesmo tamanho e data: o conteúdo não volta a ser lido
+	info, _ := os.Stat(imagePath)
+	os.WriteFile(imagePath, []byte("imagem mal"), 0644)
+	os.Chtimes(imagePath, info.ModTime(), info.ModTime())
+	if err := verifyCachedImage("debian", distroInfo, saved, imagePath); err != nil {
+		t.Errorf("imagem inalterada verificada de novo: %v", err)
+	}
+
+	// Com outra data de modificação, o checksum é verificado outra vez
+	os.Chtimes(imagePath, info.ModTime().Add(time.Minute), info.ModTime().Add(time.Minute))
+	if err := verifyCachedImage("debian", distroInfo, saved, imagePath); err == nil {
+		t.Error("imagem alterada passou na verificação")
+	}
+}
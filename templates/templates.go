@@ -0,0 +1,10 @@
+// Package templates contém as definições de distros e os templates cloud-init padrão,
+// embutidos no binário para que o kvm-compose funcione a partir de qualquer diretório.
+package templates
+
+import "embed"
+
+// FS contém os ficheiros INI das distros e os templates cloud-init (*.tmpl) padrão
+//
+//go:embed *.ini *.tmpl
+var FS embed.FS